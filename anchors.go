@@ -0,0 +1,41 @@
+package checklinks
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ExtractAnchors returns the anchors of the given node's tree a URL fragment
+// can refer to: the id attributes of all elements, and the name attributes of
+// <a> elements.
+func ExtractAnchors(node *html.Node) map[string]bool {
+	anchors := make(map[string]bool)
+	var extract func(*html.Node)
+	extract = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			for _, attr := range node.Attr {
+				if attr.Key == "id" || (attr.Key == "name" && node.Data == "a") {
+					anchors[attr.Val] = true
+				}
+			}
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			extract(c)
+		}
+	}
+	extract(node)
+	return anchors
+}
+
+// checkAnchor returns an error if the given link's fragment doesn't refer to
+// any of the given anchors of the page it points to. An empty fragment and
+// "top" refer to the top of the page, and are always valid.
+func checkAnchor(l *Link, anchors map[string]bool) error {
+	fragment := l.URL.Fragment
+	if fragment == "" || strings.EqualFold(fragment, "top") || anchors[fragment] {
+		return nil
+	}
+	return fmt.Errorf("missing anchor #%s", fragment)
+}
@@ -0,0 +1,105 @@
+package checklinks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractAnchors(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<h1 id="title">Title</h1>
+		<a name="legacy">legacy</a>
+		<input name="field">
+		<section id="usage"><p id="">empty</p></section>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"title": true, "legacy": true, "usage": true, "": true}
+	if actual := ExtractAnchors(doc); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected anchors %v, got %v", expected, actual)
+	}
+}
+
+func TestCheckAnchors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<h1 id="intro">Intro</h1>
+				<a href="#intro">intro</a>
+				<a href="#outro">outro</a>
+				<a href="#top">top</a>
+				<a href="/docs.html#install">install</a>
+				<a href="/docs.html#usage">usage</a>
+				<a href="/missing.html#anchor">missing</a>`)
+		case "/docs.html":
+			fmt.Fprint(w, `<a href="/#intro">back</a><a name="install">Install</a>
+				<a href="#install">install</a><a href="#uninstall">uninstall</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+
+	for _, checkAnchors := range []bool{false, true} {
+		opts.CheckAnchors = checkAnchors
+		var missing []string
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			if r.Status() == StatusFailed && r.Link.URL.Fragment != "" {
+				missing = append(missing, r.Link.URL.RequestURI()+"#"+r.Link.URL.Fragment)
+			}
+		})
+		sort.Strings(missing)
+		expected := []string{"/missing.html#anchor"}
+		if checkAnchors {
+			expected = []string{"/#outro", "/docs.html#uninstall", "/docs.html#usage", "/missing.html#anchor"}
+		}
+		if !isEqual(missing, expected) {
+			t.Errorf("expected missing anchors %v (check: %v), got %v", expected, checkAnchors, missing)
+		}
+	}
+}
+
+func TestCheckAnchorsAcrossSites(t *testing.T) {
+	docs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<h2 id="install">Install</h2><a href="/api.html#get">get</a><a href="/api.html#put">put</a>`)
+		case "/api.html":
+			fmt.Fprint(w, `<h2 id="get">GET</h2>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer docs.Close()
+	blog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s/#install">install</a><a href="%s/#uninstall">uninstall</a>`, docs.URL, docs.URL)
+	}))
+	defer blog.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.CheckAnchors = true
+	seeds := []*Link{
+		{URL: mustParse(blog.URL + "/"), Orig: mustParse(blog.URL + "/")},
+		{URL: mustParse(docs.URL + "/"), Orig: mustParse(docs.URL + "/")},
+	}
+	var missing []string
+	crawl(context.Background(), newClient(&opts), seeds, opts, func(r *Result) {
+		if r.Status() == StatusFailed {
+			missing = append(missing, r.Link.URL.String())
+		}
+	})
+	sort.Strings(missing)
+	expected := []string{docs.URL + "/#uninstall", docs.URL + "/api.html#put"}
+	if !isEqual(missing, expected) {
+		t.Errorf("expected missing anchors %v, got %v", expected, missing)
+	}
+}
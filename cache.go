@@ -0,0 +1,147 @@
+package checklinks
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRecheckAfter is the duration after which the links recorded in a
+// LinkCache are checked again, unless configured otherwise.
+const DefaultRecheckAfter = 24 * time.Hour
+
+// CacheEntry records the last successful check of an external link.
+type CacheEntry struct {
+	// URL is the link's URL without fragment.
+	URL string `json:"url"`
+
+	// Checked is the time the link has been checked at.
+	Checked time.Time `json:"checked"`
+
+	// Status is the result's status, which is always "OK", because only
+	// successfully checked links are recorded.
+	Status string `json:"status"`
+
+	// StatusCode is the HTTP status code of the response to the link.
+	StatusCode int `json:"status_code"`
+}
+
+// linkCacheFile is the format of a LinkCache's file: a JSON object whose
+// "links" are the entries sorted by their URLs.
+type linkCacheFile struct {
+	Links []CacheEntry `json:"links"`
+}
+
+// LinkCache records the external links checked successfully, so that the
+// crawls of scheduled runs can skip the ones verified recently (see
+// CrawlOptions.Cache). An entry is valid for RecheckAfter since its link has
+// been checked: until then, the link is reported as ignored without being
+// checked again. Checking a link again, once its entry has expired, renews the
+// entry if the link still works, and removes it otherwise. Internal links are
+// never recorded, but always checked. A LinkCache can be used concurrently.
+type LinkCache struct {
+	// RecheckAfter is the duration after which a link is checked again.
+	// Zero means that links are always checked again.
+	RecheckAfter time.Duration
+
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	now     func() time.Time
+}
+
+// NewLinkCache creates an empty LinkCache, whose links are checked again after
+// the given duration.
+func NewLinkCache(recheckAfter time.Duration) *LinkCache {
+	return &LinkCache{
+		RecheckAfter: recheckAfter,
+		entries:      make(map[string]CacheEntry),
+		now:          time.Now,
+	}
+}
+
+// ReadLinkCache reads a LinkCache written by LinkCache.Write, whose links are
+// checked again after the given duration. The expired entries are dropped. An
+// empty input is read as an empty cache.
+func ReadLinkCache(r io.Reader, recheckAfter time.Duration) (*LinkCache, error) {
+	cache := NewLinkCache(recheckAfter)
+	var file linkCacheFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil && err != io.EOF {
+		return nil, err
+	}
+	for _, entry := range file.Links {
+		if u, err := url.Parse(entry.URL); err == nil && cache.valid(entry) {
+			cache.entries[visitKey(u)] = entry
+		}
+	}
+	return cache, nil
+}
+
+// Write writes the unexpired entries of the cache as a JSON object, whose
+// "links" are sorted by their URLs, e.g.:
+//
+//	{"links":[{"url":"https://example.com/","checked":"2024-05-01T02:00:00Z","status":"OK","status_code":200}]}
+func (c *LinkCache) Write(w io.Writer) error {
+	c.mu.Lock()
+	file := linkCacheFile{Links: make([]CacheEntry, 0, len(c.entries))}
+	for _, entry := range c.entries {
+		if c.valid(entry) {
+			file.Links = append(file.Links, entry)
+		}
+	}
+	c.mu.Unlock()
+	sort.Slice(file.Links, func(i, j int) bool {
+		return file.Links[i].URL < file.Links[j].URL
+	})
+	return json.NewEncoder(w).Encode(file)
+}
+
+// Len returns the number of entries in the cache, including the expired ones.
+func (c *LinkCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// checked returns the time the link with the given visit key has been checked
+// successfully at, and true if that entry is still valid, or false otherwise.
+func (c *LinkCache) checked(key string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !c.valid(entry) {
+		return time.Time{}, false
+	}
+	return entry.Checked, true
+}
+
+// record updates the entry of the given result's link, recorded under the
+// given visit key: links checked successfully are recorded, failed links and
+// warnings are removed. Other results (e.g. ignored or skipped links) and
+// internal links leave the cache unchanged.
+func (c *LinkCache) record(key string, result *Result) {
+	if result.Link.IsInternal() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch status := result.Status(); status {
+	case StatusOK:
+		c.entries[key] = CacheEntry{
+			URL:        key,
+			Checked:    c.now(),
+			Status:     status.String(),
+			StatusCode: result.StatusCode,
+		}
+	case StatusFailed, StatusWarning:
+		delete(c.entries, key)
+	}
+}
+
+// valid returns true if the given entry has not expired yet, and false
+// otherwise.
+func (c *LinkCache) valid(entry CacheEntry) bool {
+	return entry.Status == StatusOK.String() && c.now().Sub(entry.Checked) < c.RecheckAfter
+}
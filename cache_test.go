@@ -0,0 +1,143 @@
+package checklinks
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLinkCacheReadWrite(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	input := fmt.Sprintf(`{"links":[
+		{"url":"https://b.com/","checked":%q,"status":"OK","status_code":200},
+		{"url":"https://a.com/#top","checked":%q,"status":"OK","status_code":301},
+		{"url":"https://old.com/","checked":%q,"status":"OK","status_code":200},
+		{"url":"https://failed.com/","checked":%q,"status":"FAIL","status_code":404}
+	]}`, now.Add(-time.Hour).Format(time.RFC3339), now.Add(-2*time.Hour).Format(time.RFC3339),
+		now.Add(-48*time.Hour).Format(time.RFC3339), now.Format(time.RFC3339))
+	cache, err := ReadLinkCache(strings.NewReader(input), DefaultRecheckAfter)
+	if err != nil {
+		t.Fatalf("read cache: %v", err)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected the expired and failed entries to be dropped, got %d entries", cache.Len())
+	}
+	if checked, ok := cache.checked("https://a.com/"); !ok || !checked.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("expected https://a.com/ checked at %v, got %v (%t)", now.Add(-2*time.Hour), checked, ok)
+	}
+
+	var output bytes.Buffer
+	if err := cache.Write(&output); err != nil {
+		t.Fatalf("write cache: %v", err)
+	}
+	written, err := ReadLinkCache(&output, DefaultRecheckAfter)
+	if err != nil {
+		t.Fatalf("read written cache: %v", err)
+	}
+	var urls []string
+	for _, entry := range written.entries {
+		urls = append(urls, entry.URL)
+	}
+	sort.Strings(urls)
+	if expected := []string{"https://a.com/#top", "https://b.com/"}; !isEqual(urls, expected) {
+		t.Errorf("expected cached URLs %v, got %v", expected, urls)
+	}
+
+	empty, err := ReadLinkCache(strings.NewReader(""), DefaultRecheckAfter)
+	if err != nil || empty.Len() != 0 {
+		t.Errorf("expected empty cache, got %v", err)
+	}
+	if _, err := ReadLinkCache(strings.NewReader("{"), DefaultRecheckAfter); err == nil {
+		t.Error("expected error reading malformed cache")
+	}
+}
+
+func TestCrawlCache(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	broken := false
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits[r.URL.Path]++
+		if r.URL.Path == "/gone" || (r.URL.Path == "/flaky" && broken) {
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits["internal "+r.URL.Path]++
+		if r.URL.Path == "/" {
+			fmt.Fprintf(w, `<a href="/about">about</a><a href="%[1]s/ok">ok</a>
+				<a href="%[1]s/flaky">flaky</a><a href="%[1]s/gone">gone</a>`, externalURL)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	cache := NewLinkCache(time.Hour)
+	cache.now = func() time.Time { return now }
+	crawl := func() map[string]Status {
+		opts := DefaultCrawlOptions()
+		opts.Cache = cache
+		statuses := make(map[string]Status)
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			statuses[r.Link.URL.Path] = r.Status()
+		})
+		return statuses
+	}
+	resetHits := func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		previous := hits
+		hits = make(map[string]int)
+		return previous
+	}
+
+	statuses := crawl()
+	if statuses["/ok"] != StatusOK || statuses["/flaky"] != StatusOK || statuses["/gone"] != StatusFailed {
+		t.Errorf("expected external links to be checked, got %v", statuses)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 links to be cached, got %d", cache.Len())
+	}
+	resetHits()
+
+	// Within the hour, the links checked successfully are skipped, but the
+	// internal and the failed links are checked again.
+	now = now.Add(30 * time.Minute)
+	statuses = crawl()
+	if statuses["/ok"] != StatusIgnored || statuses["/flaky"] != StatusIgnored || statuses["/gone"] != StatusFailed {
+		t.Errorf("expected cached links to be ignored, got %v", statuses)
+	}
+	if h := resetHits(); h["/ok"] != 0 || h["/flaky"] != 0 || h["/gone"] == 0 ||
+		h["internal /"] == 0 || h["internal /about"] == 0 {
+		t.Errorf("expected only the uncached links to be requested, got %v", h)
+	}
+
+	// After the hour, the links are checked again, and the broken one is
+	// removed from the cache.
+	now = now.Add(time.Hour)
+	mu.Lock()
+	broken = true
+	mu.Unlock()
+	statuses = crawl()
+	if statuses["/ok"] != StatusOK || statuses["/flaky"] != StatusFailed {
+		t.Errorf("expected expired links to be checked again, got %v", statuses)
+	}
+	if _, ok := cache.checked(visitKey(mustParse(externalURL + "/ok"))); !ok {
+		t.Error("expected /ok to be renewed in the cache")
+	}
+	if _, ok := cache.checked(visitKey(mustParse(externalURL + "/flaky"))); ok {
+		t.Error("expected /flaky to be removed from the cache")
+	}
+}
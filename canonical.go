@@ -0,0 +1,53 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/url"
+
+	"golang.org/x/net/html"
+)
+
+// extractCanonical returns the href of the given document's first <link
+// rel="canonical">, and true, or false if there is none.
+func extractCanonical(doc *html.Node) (string, bool) {
+	for _, element := range findElements(doc, "link") {
+		link := Link{Element: "link", Rel: attribute(element, "rel")}
+		if href := attribute(element, "href"); link.hasRel("canonical") && href != "" {
+			return href, true
+		}
+	}
+	return "", false
+}
+
+// checkCanonical returns a warning if the given canonical href, declared by
+// the given page, whose final URL (after redirects) is given, refers to
+// another page, and nil otherwise. A canonical URL refers to the page itself
+// if it has the same path and query, and an internal host (see
+// Link.IsInternal), e.g. the production site's host declared by the pages of
+// a staging site.
+func checkCanonical(page *Link, pageURL *url.URL, href string) error {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return fmt.Errorf("%w: malformed canonical URL %q", errWarning, href)
+	}
+	canonical := &Link{
+		URL:           pageURL.ResolveReference(ref),
+		Orig:          pageURL,
+		hosts:         page.hosts,
+		internalHosts: page.internalHosts,
+		comparePorts:  page.comparePorts,
+	}
+	if canonical.IsInternal() && canonicalPath(canonical.URL) == canonicalPath(pageURL) &&
+		canonical.URL.RawQuery == pageURL.RawQuery {
+		return nil
+	}
+	return fmt.Errorf("%w: canonical URL %s differs from the page's URL %s", errWarning, canonical.URL, pageURL)
+}
+
+// canonicalPath returns the given URL's path, which is "/" if empty.
+func canonicalPath(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
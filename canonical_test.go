@@ -0,0 +1,91 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCheckCanonical(t *testing.T) {
+	tests := []struct {
+		page      string
+		canonical string
+		valid     bool
+	}{
+		{"https://example.com/", "https://example.com/", true},
+		{"https://example.com", "/", true},
+		{"https://example.com/docs/", "", true},
+		{"https://example.com/docs/", "/docs/", true},
+		{"https://example.com/docs/?page=2", "?page=2", true},
+		{"https://example.com/docs/", "https://www.example.com/docs/", true},
+		{"https://example.com/docs/", "/docs", false},
+		{"https://example.com/docs/?page=2", "/docs/", false},
+		{"https://example.com/docs/", "https://other.com/docs/", false},
+		{"https://example.com/docs/", "https://example.com/%zz", false},
+	}
+	for _, test := range tests {
+		page := &Link{URL: mustParse(test.page), Orig: mustParse(test.page), internalHosts: []string{"www.example.com"}}
+		if err := checkCanonical(page, page.URL, test.canonical); (err == nil) != test.valid {
+			t.Errorf("expected canonical %q of %s to be valid: %t, got %v", test.canonical, test.page, test.valid, err)
+		}
+	}
+}
+
+func TestCrawlCanonical(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<link rel="canonical" href="/"><a href="/moved">moved</a>
+				<a href="/staging">staging</a><a href="/copy">copy</a>`)
+		case "/moved":
+			// The page declares a canonical URL that doesn't exist.
+			fmt.Fprint(w, `<link rel="canonical" href="/moved-here">`)
+		case "/staging":
+			fmt.Fprint(w, `<link rel="canonical" href="https://www.example.com/staging">`)
+		case "/copy":
+			fmt.Fprint(w, `<link rel="canonical" href="/original">`)
+		case "/original":
+			fmt.Fprint(w, `<p>original</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.CheckCanonical = true
+	opts.WarnCanonical = true
+	opts.InternalHosts = []string{"www.example.com"}
+	opts.Exclude = []*regexp.Regexp{regexp.MustCompile(`^https://www\.example\.com/`)}
+	var warnings []string
+	statuses := make(map[string]Status)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusWarning {
+			warnings = append(warnings, r.Link.URL.Path)
+			return
+		}
+		statuses[r.Link.URL.Host+r.Link.URL.Path] = r.Status()
+	})
+	sort.Strings(warnings)
+	if expected := []string{"/copy", "/moved"}; !isEqual(warnings, expected) {
+		t.Errorf("expected canonical warnings for %v, got %v", expected, warnings)
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	expected := map[string]Status{
+		host + "/":                StatusOK,
+		host + "/moved":           StatusOK,
+		host + "/moved-here":      StatusFailed,
+		host + "/staging":         StatusOK,
+		host + "/copy":            StatusOK,
+		host + "/original":        StatusOK,
+		"www.example.com/staging": StatusIgnored,
+	}
+	if fmt.Sprint(statuses) != fmt.Sprint(expected) {
+		t.Errorf("expected results %v, got %v", expected, statuses)
+	}
+}
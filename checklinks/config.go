@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// loadConfig reads the JSON config file with the given path, which contains an
+// object with flag names as keys, e.g. {"timeout": 5, "include": ["^https"]}.
+// The values of repeatable flags can be given as arrays. The flags of the
+// given flag set are set to the values of the file, unless they have already
+// been set on the command line.
+func loadConfig(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	// Numbers are kept as written, because large ones would be formatted in
+	// exponent form (e.g. 1e+06) as float64, which the flags cannot parse.
+	var config map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&config); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	setOnCommandLine := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+	for name, value := range config {
+		if fs.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("config %s: unknown option %q", path, name)
+		}
+		if setOnCommandLine[name] {
+			continue
+		}
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		for _, v := range values {
+			switch v.(type) {
+			case string, json.Number, bool:
+			default:
+				return fmt.Errorf("config %s: invalid value for %q: %v", path, name, v)
+			}
+			if err := fs.Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("config %s: invalid value for %q: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	fs := flag.NewFlagSet("checklinks", flag.ContinueOnError)
+	maxBodySize := fs.Int64("max-body-size", 0, "")
+	parallelism := fs.Int("parallelism", 1, "")
+	delay := fs.Duration("delay", 0, "")
+	success := fs.Bool("success", false, "")
+	timeout := fs.Int("timeout", 10, "")
+	var include domainList
+	fs.Var(&include, "include", "")
+	if err := fs.Parse([]string{"-timeout", "3"}); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "checklinks.json")
+	config := `{"max-body-size": 1048576, "parallelism": 1000000, "delay": "1.5s", "success": true,
+		"timeout": 5, "include": ["^https://a", "^https://b"]}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := loadConfig(fs, path); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if *maxBodySize != 1048576 || *parallelism != 1000000 {
+		t.Errorf("expected large numbers to be set, got %d and %d", *maxBodySize, *parallelism)
+	}
+	if *delay != 1500*time.Millisecond || !*success {
+		t.Errorf("expected delay and success to be set, got %v and %t", *delay, *success)
+	}
+	if *timeout != 3 {
+		t.Errorf("expected timeout set on the command line to be kept, got %d", *timeout)
+	}
+	if len(include) != 2 {
+		t.Errorf("expected both includes to be set, got %v", include)
+	}
+
+	for _, invalid := range []string{`{"parallelism": 1.5}`, `{"unknown": 1}`, `{"success": null}`, `{`} {
+		if err := os.WriteFile(path, []byte(invalid), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		fs := flag.NewFlagSet("checklinks", flag.ContinueOnError)
+		fs.Int("parallelism", 1, "")
+		fs.Bool("success", false, "")
+		if err := loadConfig(fs, path); err == nil {
+			t.Errorf("expected error loading config %s", invalid)
+		}
+	}
+}
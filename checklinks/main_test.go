@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunCache(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits++
+	}))
+	defer external.Close()
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s/ok">ok</a>`, externalURL)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "cache.json")
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"checklinks", "-summary=false", "-cache", cache, "-o", filepath.Join(dir, "results.txt"), srv.URL + "/"}
+
+	if code := run(); code != 0 {
+		t.Fatalf("expected first run to exit with 0, got %d", code)
+	}
+	if _, err := os.Stat(cache); err != nil {
+		t.Fatalf("expected cache file to be written: %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("expected external link to be requested once, got %d requests", hits)
+	}
+	if code := run(); code != 0 {
+		t.Fatalf("expected second run to exit with 0, got %d", code)
+	}
+	if hits != 1 {
+		t.Errorf("expected second run to skip the cached link, got %d requests", hits)
+	}
+}
@@ -2,10 +2,28 @@ package checklinks
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"golang.org/x/net/html"
 )
@@ -38,6 +56,66 @@ func TestExtractTagAttribute(t *testing.T) {
 	}
 }
 
+func TestExtractLinks(t *testing.T) {
+	page := mustParse("https://example.com/articles/")
+	for _, document := range []string{htmlDocument, resourceDocument, linkDocument} {
+		root, _ := html.Parse(strings.NewReader(document))
+		links := ExtractLinks(root, page)
+		// Every attribute's links are extracted like by ExtractTagAttribute,
+		// and qualified like the crawl does.
+		var expected []string
+		for _, attribute := range append(append([]TagAttribute{}, LinkAttributes...), ResourceAttributes...) {
+			for _, value := range ExtractTagAttribute(root, attribute.Tag, attribute.Attr) {
+				addresses := []string{value}
+				if attribute.Attr == "srcset" {
+					addresses = ParseSrcset(value)
+				}
+				for _, address := range addresses {
+					link, _ := NewLink(address, page)
+					if link.isSameOrigin() {
+						link.URL = QualifyInternalURL(page, link.URL)
+					}
+					expected = append(expected, attribute.Tag+" "+link.URL.String())
+				}
+			}
+		}
+		var actual []string
+		for _, link := range links {
+			actual = append(actual, link.Element+" "+link.URL.String())
+			if link.Orig != page {
+				t.Errorf("expected link %s to be from %s, was from %s", link.URL, page, link.Orig)
+			}
+		}
+		if !isEqual(actual, expected) {
+			t.Errorf("expected links %v, got %v", expected, actual)
+		}
+	}
+}
+
+func TestExtractLinksBase(t *testing.T) {
+	root, _ := html.Parse(strings.NewReader(`<head><base href="/docs/v2/"></head>
+		<a href="intro.html">intro</a>
+		<a href="/about">about</a>
+		<a href="https://other.com/page">other</a>
+		<a href="mailto:info@example.com">mail</a>
+		<link rel="stylesheet" href="../style.css">`))
+	links := ExtractLinks(root, mustParse("https://example.com/index.html"))
+	var actual []string
+	for _, link := range links {
+		actual = append(actual, link.URL.String()+" "+link.Rel)
+	}
+	expected := []string{
+		"https://example.com/docs/v2/intro.html ",
+		"https://example.com/about ",
+		"https://other.com/page ",
+		"mailto:info@example.com ",
+		"https://example.com/docs/style.css stylesheet",
+	}
+	if !isEqual(actual, expected) {
+		t.Errorf("expected links %v, got %v", expected, actual)
+	}
+}
+
 func isEqual[T comparable](a []T, b []T) bool {
 	if len(a) != len(b) {
 		return false
@@ -75,6 +153,71 @@ var qualifyURLTests = []struct {
 		"milk-manifesto.html",
 		"https://paedubucher.ch/articles/drink-more-milk/milk-manifesto.html",
 	},
+	{
+		"https://paedubucher.ch/articles/",
+		"search.html?q=cheese",
+		"https://paedubucher.ch/articles/search.html?q=cheese",
+	},
+	{
+		"https://paedubucher.ch/articles/",
+		"search.html?q=cheese&sort=asc#results",
+		"https://paedubucher.ch/articles/search.html?q=cheese&sort=asc#results",
+	},
+	{
+		"https://paedubucher.ch/articles/drink-more-milk/",
+		"../eat-more-cheese/cheese-manifesto.html",
+		"https://paedubucher.ch/articles/eat-more-cheese/cheese-manifesto.html",
+	},
+	{
+		"https://paedubucher.ch/articles/drink-more-milk/",
+		"./milk-manifesto.html",
+		"https://paedubucher.ch/articles/drink-more-milk/milk-manifesto.html",
+	},
+	{
+		"https://paedubucher.ch/articles/drink-more-milk/",
+		"../",
+		"https://paedubucher.ch/articles/",
+	},
+	{
+		"https://paedubucher.ch/articles/",
+		"../../../about.html",
+		"https://paedubucher.ch/about.html",
+	},
+	{
+		"https://paedubucher.ch/articles/",
+		"/about/./team/../index.html",
+		"https://paedubucher.ch/about/index.html",
+	},
+	{
+		"https://paedubucher.ch/about.html",
+		"contact.html",
+		"https://paedubucher.ch/contact.html",
+	},
+	{
+		"https://paedubucher.ch/docs/setup.html",
+		"../about.html?lang=de",
+		"https://paedubucher.ch/about.html?lang=de",
+	},
+	{
+		"https://paedubucher.ch/about.html",
+		"#team",
+		"https://paedubucher.ch/about.html#team",
+	},
+	{
+		"https://paedubucher.ch/about.html",
+		"?lang=de",
+		"https://paedubucher.ch/about.html?lang=de",
+	},
+	{
+		"https://paedubucher.ch/articles/",
+		"?page=2#results",
+		"https://paedubucher.ch/articles/?page=2#results",
+	},
+	{
+		"https://paedubucher.ch/articles/drink-more-milk",
+		"?lang=de",
+		"https://paedubucher.ch/articles/drink-more-milk?lang=de",
+	},
 }
 
 func TestQualifyInternalRootURL(t *testing.T) {
@@ -90,21 +233,2634 @@ func TestQualifyInternalRootURL(t *testing.T) {
 }
 
 func TestLocalDemoPage(t *testing.T) {
-	fs := http.FileServer(http.Dir("demopage"))
-	srv := http.Server{
-		Addr:    "0.0.0.0:8000",
-		Handler: fs,
-	}
-	http.Handle("/", fs)
-	go srv.ListenAndServe()
-
-	done := make(chan struct{})
-	go func() {
-		pageURL, _ := url.Parse("http://localhost:8000")
-		CrawlPage(pageURL, 1, true, true, true)
-		done <- struct{}{}
-	}()
-
-	<-done
-	srv.Shutdown(context.TODO())
+	srv := httptest.NewServer(http.FileServer(http.Dir("demopage")))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.Client = srv.Client()
+	opts.InternalOnly = true
+	statuses := make(map[string]Status)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		statuses[r.Link.URL.String()] = r.Status()
+	})
+	expected := map[string]Status{
+		srv.URL + "/":                                 StatusOK,
+		srv.URL + "/about":                            StatusOK,
+		srv.URL + "/about/license.html":               StatusOK,
+		srv.URL + "/broken.hml":                       StatusFailed,
+		"mailto:me@whatev.er":                         StatusIgnored,
+		"https://github.com/patrickbucher/checklinks": StatusIgnored,
+		"https://opensource.org/licenses/MIT":         StatusIgnored,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected statuses %v, got %v", expected, statuses)
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/page">page</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	// The test server's certificate is only trusted by its own client.
+	for _, client := range []*http.Client{nil, srv.Client()} {
+		opts := DefaultCrawlOptions()
+		opts.Client = client
+		failed := CrawlPageWithOptions(mustParse(srv.URL+"/"), opts)
+		if expected := map[bool]int{true: 1, false: 0}[client == nil]; failed != expected {
+			t.Errorf("expected %d failed links (custom client: %t), got %d", expected, client != nil, failed)
+		}
+	}
+}
+
+func TestNewGetRequestUserAgent(t *testing.T) {
+	for _, userAgent := range []string{UserAgent, BrowserUserAgent, "checklinks-test", ""} {
+		opts := CrawlOptions{UserAgent: userAgent}
+		request, err := newGetRequest(context.TODO(), "https://paedubucher.ch", &opts)
+		if err != nil {
+			t.Fatalf("prepare request: %v", err)
+		}
+		if got := request.Header.Get("User-Agent"); got != userAgent {
+			t.Errorf("expected User-Agent '%s', got '%s'", userAgent, got)
+		}
+	}
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	expected := "checklinks/" + Version + " (+https://github.com/patrickbucher/checklinks)"
+	if actual := DefaultCrawlOptions().UserAgent; actual != expected {
+		t.Errorf("expected default User-Agent %q, got %q", expected, actual)
+	}
+}
+
+const fragmentDocument = `
+<!DOCTYPE html>
+<html>
+	<body>
+		<a href="/page.html">page</a>
+		<a href="/page.html#section">section</a>
+	</body>
+</html>
+`
+
+func TestDedupIgnoresFragment(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, fragmentDocument)
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	CrawlPageWithOptions(pageURL, DefaultCrawlOptions())
+
+	if n := hits["/page.html"]; n != 1 {
+		t.Errorf("expected /page.html to be fetched once, got %d fetches", n)
+	}
+}
+
+func TestCrawlReportsFailedCount(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/ok.html">ok</a><a href="/a.html">a</a><a href="/b.html">b</a>`)
+		case "/ok.html":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	opts := DefaultCrawlOptions()
+	opts.ReportFailed = false
+	if failed := CrawlPageWithOptions(pageURL, opts); failed != 2 {
+		t.Errorf("expected 2 failed links, got %d", failed)
+	}
+}
+
+func TestCrawlMaxDuration(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/slow/1">1</a><a href="/slow/2">2</a><a href="/slow/3">3</a>`)
+			return
+		}
+		select {
+		case <-time.After(10 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	opts := DefaultCrawlOptions()
+	opts.Timeout = 0
+	opts.MaxDuration = 200 * time.Millisecond
+	start := time.Now()
+	failed := CrawlPageWithOptions(pageURL, opts)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected crawl to be aborted after %v, took %v", opts.MaxDuration, elapsed)
+	}
+	if failed != 0 {
+		t.Errorf("expected aborted links to be skipped, got %d failed", failed)
+	}
+}
+
+func TestRedirectLoop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	_, err := FetchDocument(srv.URL+"/a", newClient(&opts), &opts)
+	if !errors.Is(err, errRedirectLoop) {
+		t.Errorf("expected redirect loop error, got %v", err)
+	}
+}
+
+func TestCrawlPathPrefix(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/docs/">docs</a><a href="/blog/">blog</a>`)
+		case "/docs/":
+			fmt.Fprint(w, `<a href="intro.html">intro</a>`)
+		case "/blog/":
+			fmt.Fprint(w, `<a href="first-post.html">first post</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	opts := DefaultCrawlOptions()
+	opts.PathPrefix = "/docs/"
+	CrawlPageWithOptions(pageURL, opts)
+
+	for path, expected := range map[string]int{
+		"/":                     1,
+		"/docs/":                1,
+		"/docs/intro.html":      1,
+		"/blog/":                1,
+		"/blog/first-post.html": 0,
+	} {
+		if hits[path] != expected {
+			t.Errorf("expected %s to be fetched %d times, got %d", path, expected, hits[path])
+		}
+	}
+}
+
+var filterURLTests = []struct {
+	url     string
+	include []string
+	exclude []string
+	ignored bool
+}{
+	{"https://paedubucher.ch/articles/", nil, nil, false},
+	{"https://paedubucher.ch/articles/", []string{"/articles/"}, nil, false},
+	{"https://paedubucher.ch/about/", []string{"/articles/"}, nil, true},
+	{"https://paedubucher.ch/about/", []string{"/articles/", "/about/"}, nil, false},
+	{"https://paedubucher.ch/articles/", nil, []string{`\.pdf$`}, false},
+	{"https://paedubucher.ch/cv.pdf", nil, []string{`\.pdf$`}, true},
+	{"https://paedubucher.ch/cv.pdf", []string{"paedubucher"}, []string{`\.pdf$`}, true},
+}
+
+func TestFilterURL(t *testing.T) {
+	compile := func(patterns []string) []*regexp.Regexp {
+		compiled := make([]*regexp.Regexp, 0)
+		for _, pattern := range patterns {
+			compiled = append(compiled, regexp.MustCompile(pattern))
+		}
+		return compiled
+	}
+	for _, testCase := range filterURLTests {
+		err := filterURL(testCase.url, compile(testCase.include), compile(testCase.exclude))
+		if testCase.ignored && !errors.Is(err, errIgnored) {
+			t.Errorf("expected %s to be ignored (include: %v, exclude: %v), got %v",
+				testCase.url, testCase.include, testCase.exclude, err)
+		}
+		if !testCase.ignored && err != nil {
+			t.Errorf("expected %s not to be ignored (include: %v, exclude: %v), got %v",
+				testCase.url, testCase.include, testCase.exclude, err)
+		}
+	}
+}
+
+const resourceDocument = `
+<!DOCTYPE html>
+<html>
+	<head>
+		<link rel="icon" href="/favicon.ico">
+		<script src="/js/app.js"></script>
+		<script>console.log("inline");</script>
+	</head>
+	<body>
+		<iframe src="/embedded.html"></iframe>
+		<img src="/img/a.jpg" srcset="/img/a.jpg 1x, /img/a@2x.jpg 2x">
+		<picture>
+			<source srcset="/img/b-480.webp 480w, /img/b-800.webp 800w">
+			<img src="/img/b.jpg">
+		</picture>
+	</body>
+</html>
+`
+
+const mediaDocument = `
+<!DOCTYPE html>
+<html>
+	<body>
+		<video controls>
+			<source src="/media/intro.webm" type="video/webm">
+			<source src="/media/intro.mp4" type="video/mp4">
+		</video>
+		<audio src="/media/theme.ogg"></audio>
+		<audio controls>
+			<source src="/media/podcast.mp3" type="audio/mpeg">
+		</audio>
+		<picture>
+			<source srcset="/img/c.webp">
+			<img src="/img/c.jpg">
+		</picture>
+	</body>
+</html>
+`
+
+func TestExtractMediaLinks(t *testing.T) {
+	root, _ := html.Parse(strings.NewReader(mediaDocument))
+	var actual []string
+	for _, link := range ExtractLinks(root, mustParse("https://example.com/")) {
+		if strings.HasPrefix(link.URL.Path, "/media/") {
+			actual = append(actual, link.Element+" "+link.URL.Path)
+		}
+	}
+	sort.Strings(actual)
+	expected := []string{
+		"audio /media/podcast.mp3",
+		"audio /media/theme.ogg",
+		"video /media/intro.mp4",
+		"video /media/intro.webm",
+	}
+	if !isEqual(actual, expected) {
+		t.Errorf("expected media links %v, got %v", expected, actual)
+	}
+}
+
+func TestCrawlMedia(t *testing.T) {
+	var mu sync.Mutex
+	methods := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods[r.URL.Path] = r.Method
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, mediaDocument)
+		case "/media/intro.mp4":
+			// A large video, which must not be downloaded.
+			w.Header().Set("Content-Type", "video/mp4")
+			w.Header().Set("Content-Length", strconv.Itoa(1<<30))
+		case "/media/intro.webm", "/media/theme.ogg":
+			w.Header().Set("Content-Type", "video/webm")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.Resources = ResourceAttributes
+	opts.MaxBodySize = 1 << 20
+	opts.CheckContentType = true
+	statuses := make(map[string]string)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if strings.HasPrefix(r.Link.URL.Path, "/media/") {
+			statuses[r.Link.Element+" "+r.Link.URL.Path] = r.Status().String()
+		}
+	})
+	expected := map[string]string{
+		"video /media/intro.webm":  "OK",
+		"video /media/intro.mp4":   "WARN",
+		"audio /media/theme.ogg":   "WARN",
+		"audio /media/podcast.mp3": "FAIL",
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected media results %v, got %v", expected, statuses)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for path, method := range methods {
+		if strings.HasPrefix(path, "/media/") && method != http.MethodHead {
+			t.Errorf("expected %s to be checked using HEAD, was %s", path, method)
+		}
+	}
+}
+
+func TestCrawlResources(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, resourceDocument)
+		case "/embedded.html":
+			fmt.Fprint(w, `<a href="/embedded-link.html">embedded link</a>`)
+		case "/js/app.js", "/embedded-link.html", "/img/a.jpg", "/img/a@2x.jpg", "/img/b-480.webp", "/img/b.jpg":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	opts := DefaultCrawlOptions()
+	opts.ReportFailed = false
+	if failed := CrawlPageWithOptions(pageURL, opts); failed != 0 {
+		t.Errorf("expected resources not to be checked by default, got %d failed", failed)
+	}
+
+	opts.Resources = ResourceAttributes
+	if failed := CrawlPageWithOptions(pageURL, opts); failed != 2 {
+		t.Errorf("expected the missing favicon and image to fail, got %d failed", failed)
+	}
+	for _, image := range []string{"/img/a.jpg", "/img/a@2x.jpg", "/img/b-480.webp", "/img/b-800.webp", "/img/b.jpg"} {
+		if hits[image] != 1 {
+			t.Errorf("expected image %s to be checked once, got %d requests", image, hits[image])
+		}
+	}
+	if hits["/embedded-link.html"] != 0 {
+		t.Errorf("expected iframe not to be crawled")
+	}
+
+	opts.CrawlIframes = true
+	CrawlPageWithOptions(pageURL, opts)
+	if hits["/embedded-link.html"] != 1 {
+		t.Errorf("expected iframe to be crawled")
+	}
+}
+
+const linkDocument = `<!DOCTYPE html>
+<html>
+<body>
+	<img src="/map.png" usemap="#map">
+	<map name="map">
+		<area shape="rect" coords="0,0,10,10" href="/mapped.html">
+		<area shape="rect" coords="10,10,20,20" href="/missing.html">
+	</map>
+	<form action="/search"><input name="q"></form>
+	<form action="/subscribe" method="post"><input name="email"></form>
+	<form action="/gone" method="post"><input name="email"></form>
+</body>
+</html>`
+
+func TestCrawlLinkAttributes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, linkDocument)
+		case "/mapped.html":
+			fmt.Fprint(w, `<a href="/from-map.html">from map</a>`)
+		case "/from-map.html", "/search":
+			fmt.Fprint(w, "ok")
+		case "/subscribe":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	statuses := make(map[string]Status)
+	CrawlPageFunc(mustParse(srv.URL), DefaultCrawlOptions(), func(r *Result) {
+		statuses[r.Link.URL.Path] = r.Status()
+	})
+	expected := map[string]Status{
+		"/":              StatusOK,
+		"/mapped.html":   StatusOK,
+		"/from-map.html": StatusOK,
+		"/missing.html":  StatusFailed,
+		"/search":        StatusOK,
+		"/subscribe":     StatusIgnored,
+		"/gone":          StatusFailed,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected statuses %v, got %v", expected, statuses)
+	}
+}
+
+func TestCrawlBase(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<base href="/docs/"><a href="intro.html">intro</a>`)
+		case "/docs/intro.html":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var checked []string
+	CrawlPageFunc(mustParse(srv.URL+"/"), DefaultCrawlOptions(), func(r *Result) {
+		if r.Status() != StatusOK {
+			t.Errorf("expected %s to be OK, got %v", r.Link.URL, r.Err)
+		}
+		checked = append(checked, r.Link.URL.Path)
+	})
+	sort.Strings(checked)
+	if expected := []string{"/", "/docs/intro.html"}; !isEqual(checked, expected) {
+		t.Errorf("expected links %v to be checked, got %v", expected, checked)
+	}
+}
+
+func TestCrawlPositions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, positionDocument)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.Resources = ResourceAttributes
+	positions := make(map[string]string)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		positions[r.Link.URL.Path] = fmt.Sprintf("%d:%d", r.Link.Line, r.Link.Column)
+	})
+	expected := map[string]string{
+		"/":            "0:0",
+		"/about":       "7:14",
+		"/missing":     "8:3",
+		"/style.css":   "4:3",
+		"/logo.png":    "10:3",
+		"/logo@2x.png": "10:3",
+	}
+	for path, position := range expected {
+		if positions[path] != position {
+			t.Errorf("expected %s to be at %s, got %s", path, position, positions[path])
+		}
+	}
+}
+
+func TestCrawlPageFunc(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/ok.html">ok</a><a href="/missing.html">missing</a><a href="mailto:a@b.c">mail</a>`)
+		case "/ok.html":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	statuses := make(map[Status]int)
+	CrawlPageFunc(pageURL, DefaultCrawlOptions(), func(result *Result) {
+		statuses[result.Status()]++
+	})
+	expected := map[Status]int{StatusOK: 2, StatusIgnored: 1, StatusFailed: 1}
+	for status, n := range expected {
+		if statuses[status] != n {
+			t.Errorf("expected %d results with status %s, got %d", n, status, statuses[status])
+		}
+	}
+}
+
+func TestFetchLeafFallsBackToGet(t *testing.T) {
+	var mu sync.Mutex
+	methods := make([]string, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		leafMethod string
+		soft404    bool
+		status     int
+		expected   []string
+	}{
+		{"", false, http.StatusOK, []string{http.MethodHead, http.MethodGet}},
+		{http.MethodGet, false, http.StatusOK, []string{http.MethodGet}},
+		{http.MethodHead, false, http.StatusMethodNotAllowed, []string{http.MethodHead}},
+		{http.MethodHead, true, http.StatusOK, []string{http.MethodGet}},
+	}
+	for _, test := range tests {
+		mu.Lock()
+		methods = methods[:0]
+		mu.Unlock()
+		opts := DefaultCrawlOptions()
+		opts.LeafMethod = test.leafMethod
+		if test.soft404 {
+			opts.Soft404Pattern = DefaultSoft404Pattern
+		}
+		response, err := fetchLeaf(context.TODO(), srv.Client(), &opts, srv.URL)
+		if err != nil {
+			t.Fatalf("fetch leaf: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != test.status {
+			t.Errorf("expected status %d, got %d", test.status, response.StatusCode)
+		}
+		mu.Lock()
+		if !isEqual(methods, test.expected) {
+			t.Errorf("expected requests %v (method: %q, soft 404: %v), got %v", test.expected, test.leafMethod, test.soft404, methods)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestTLSVerification(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<p>self-signed</p>")
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	_, err := FetchDocument(srv.URL, newClient(&opts), &opts)
+	if err == nil || !strings.Contains(err.Error(), "invalid TLS certificate") {
+		t.Errorf("expected invalid TLS certificate error, got %v", err)
+	}
+
+	opts.Insecure = true
+	if _, err := FetchDocument(srv.URL, newClient(&opts), &opts); err != nil {
+		t.Errorf("expected self-signed certificate to be accepted when insecure, got %v", err)
+	}
+}
+
+func TestCrawlDryRun(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits["external"]++
+		mu.Unlock()
+	}))
+	defer external.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			// a different hostname than the one of srv makes the link external
+			externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="/about.html">about</a><a href="%s">external</a>`, externalURL)
+		case "/about.html":
+			fmt.Fprint(w, `<a href="/">home</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	opts := DefaultCrawlOptions()
+	opts.DryRun = true
+	statuses := make(map[Status]int)
+	CrawlPageFunc(pageURL, opts, func(result *Result) {
+		statuses[result.Status()]++
+	})
+	if hits["/about.html"] != 1 {
+		t.Errorf("expected internal page to be fetched during dry-run")
+	}
+	if hits["external"] != 0 {
+		t.Errorf("expected external link not to be fetched during dry-run")
+	}
+	if statuses[StatusSkipped] != 1 {
+		t.Errorf("expected 1 skipped link, got %d", statuses[StatusSkipped])
+	}
+}
+
+func TestCookies(t *testing.T) {
+	var mu sync.Mutex
+	externalCookies := make([]string, 0)
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		for _, cookie := range r.Cookies() {
+			externalCookies = append(externalCookies, cookie.Name)
+		}
+		mu.Unlock()
+	}))
+	defer external.Close()
+	external.URL = strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			fmt.Fprintf(w, `<a href="/members">members</a><a href="/secret">secret</a><a href="%s/">external</a>
+				<a href="/account">account</a>`, external.URL)
+		case "/account":
+			// Without a cookie, the session is set up by a bounce.
+			if _, err := r.Cookie("account"); err != nil {
+				http.Redirect(w, r, "/session", http.StatusFound)
+			}
+		case "/session":
+			http.SetCookie(w, &http.Cookie{Name: "account", Value: "42", Path: "/"})
+			http.Redirect(w, r, "/account", http.StatusFound)
+		case "/members":
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+				http.Redirect(w, r, "/login", http.StatusFound)
+			}
+		case "/secret":
+			if cookie, err := r.Cookie("token"); err != nil || cookie.Value != "xyz" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}
+		case "/login":
+			http.Redirect(w, r, "/members", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	failed := make(map[string]bool)
+	opts := DefaultCrawlOptions()
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusFailed {
+			failed[r.Link.URL.Path] = true
+		}
+	})
+	if expected := map[string]bool{"/secret": true}; !reflect.DeepEqual(failed, expected) {
+		t.Errorf("expected failed links %v without cookies given, got %v", expected, failed)
+	}
+
+	failed = make(map[string]bool)
+	opts.Cookies = []*http.Cookie{{Name: "token", Value: "xyz"}}
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusFailed {
+			failed[r.Link.URL.Path] = true
+		}
+	})
+	if len(failed) != 0 {
+		t.Errorf("expected no failed links with cookies given, got %v", failed)
+	}
+	if len(externalCookies) != 0 {
+		t.Errorf("expected no cookies to be sent to the external site, got %v", externalCookies)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	var externalAuth bool
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, externalAuth = r.BasicAuth()
+	}))
+	defer external.Close()
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "patrick" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<a href="/staging.html">staging</a><a href="%s">external</a>`, externalURL)
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	opts := DefaultCrawlOptions()
+	opts.ReportFailed = false
+	if failed := CrawlPageWithOptions(pageURL, opts); failed != 1 {
+		t.Errorf("expected start page to fail without credentials, got %d failed", failed)
+	}
+	opts.BasicAuth = &BasicAuth{Username: "patrick", Password: "secret"}
+	if failed := CrawlPageWithOptions(pageURL, opts); failed != 0 {
+		t.Errorf("expected no failures with credentials, got %d failed", failed)
+	}
+	if externalAuth {
+		t.Errorf("expected credentials not to be sent to external host")
+	}
+}
+
+var parseHeaderTests = []struct {
+	header string
+	key    string
+	value  string
+	valid  bool
+}{
+	{"X-Preview-Token: abc", "X-Preview-Token", "abc", true},
+	{"Accept-Language:de-CH, en;q=0.8", "Accept-Language", "de-CH, en;q=0.8", true},
+	{"X-Empty:", "X-Empty", "", true},
+	{"X-Preview-Token abc", "", "", false},
+	{": abc", "", "", false},
+	{"X Preview: abc", "", "", false},
+}
+
+func TestParseHeader(t *testing.T) {
+	for _, testCase := range parseHeaderTests {
+		key, value, err := ParseHeader(testCase.header)
+		if testCase.valid && err != nil {
+			t.Errorf("parse header '%s': %v", testCase.header, err)
+		} else if !testCase.valid && err == nil {
+			t.Errorf("expected error for header '%s', got none", testCase.header)
+		} else if key != testCase.key || value != testCase.value {
+			t.Errorf("expected header '%s' to be parsed as '%s'/'%s', got '%s'/'%s'",
+				testCase.header, testCase.key, testCase.value, key, value)
+		}
+	}
+}
+
+func TestNewRequestHeaders(t *testing.T) {
+	opts := CrawlOptions{UserAgent: UserAgent, Headers: http.Header{}}
+	opts.Headers.Add("X-Preview-Token", "abc")
+	opts.Headers.Add("user-agent", "checklinks-test")
+	opts.Headers.Add("Host", "preview.paedubucher.ch")
+	request, err := newGetRequest(context.TODO(), "https://paedubucher.ch", &opts)
+	if err != nil {
+		t.Fatalf("prepare request: %v", err)
+	}
+	if got := request.Header.Get("X-Preview-Token"); got != "abc" {
+		t.Errorf("expected X-Preview-Token 'abc', got '%s'", got)
+	}
+	if got := request.Header.Get("User-Agent"); got != "checklinks-test" {
+		t.Errorf("expected header to override User-Agent, got '%s'", got)
+	}
+	if request.Host != "preview.paedubucher.ch" {
+		t.Errorf("expected Host 'preview.paedubucher.ch', got '%s'", request.Host)
+	}
+}
+
+// newLinkFarm starts a server with a page linking to the given number of leaves,
+// and counts the connections opened to it.
+func newLinkFarm(leaves int, conns *int64) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			fmt.Fprint(w, "leaf")
+			return
+		}
+		for i := 0; i < leaves; i++ {
+			fmt.Fprintf(w, `<a href="/leaf-%d.pdf">leaf</a>`, i)
+		}
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(conns, 1)
+		}
+	}
+	return srv
+}
+
+func TestConnectionReuse(t *testing.T) {
+	var conns int64
+	srv := newLinkFarm(200, &conns)
+	srv.Start()
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.Parallelism = 8
+	var checked int
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusOK {
+			checked++
+		}
+	})
+	if checked != 201 {
+		t.Errorf("expected 201 links checked, got %d", checked)
+	}
+	if n := atomic.LoadInt64(&conns); n > 2*int64(opts.Parallelism) {
+		t.Errorf("expected connections to be reused, got %d connections for %d requests", n, checked)
+	}
+}
+
+func TestHTTP2(t *testing.T) {
+	var mu sync.Mutex
+	protos := make(map[string]bool)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		protos[r.Proto] = true
+		mu.Unlock()
+		fmt.Fprint(w, `<a href="/leaf">leaf</a>`)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.Insecure = true
+	if failed := CrawlPageWithOptions(mustParse(srv.URL+"/"), opts); failed != 0 {
+		t.Errorf("expected no failed links, got %d", failed)
+	}
+	if expected := map[string]bool{"HTTP/2.0": true}; !reflect.DeepEqual(protos, expected) {
+		t.Errorf("expected protocols %v, got %v", expected, protos)
+	}
+}
+
+func BenchmarkCrawl(b *testing.B) {
+	var conns int64
+	srv := newLinkFarm(500, &conns)
+	srv.Start()
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	for i := 0; i < b.N; i++ {
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(*Result) {})
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&conns))/float64(b.N), "conns/op")
+}
+
+func TestRequestsConfiguredAlike(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.Method+" "+r.URL.Path] = r.UserAgent() + "/" + r.Header.Get("X-Preview-Token")
+		mu.Unlock()
+		fmt.Fprint(w, `<a href="/leaf.pdf">leaf</a>`)
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.UserAgent = "checklinks-test"
+	opts.Headers = http.Header{"X-Preview-Token": {"abc"}}
+	opts.PathPrefix = "/docs/"
+
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(*Result) {})
+	expected := map[string]string{
+		"GET /":          "checklinks-test/abc",
+		"HEAD /leaf.pdf": "checklinks-test/abc",
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected requests %v, got %v", expected, received)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := FetchDocumentContext(ctx, srv.URL, srv.Client(), &opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected fetch to be canceled, got %v", err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	counts := map[Status]int{StatusOK: 398, StatusIgnored: 6, StatusFailed: 8}
+	expected := "Checked 412 links in 3.142s: 398 OK, 6 ignored, 8 failed"
+	if actual := summarize(412, counts, 3141592*time.Microsecond); actual != expected {
+		t.Errorf("expected summary '%s', got '%s'", expected, actual)
+	}
+	counts[StatusSkipped] = 3
+	expected = "Checked 415 links in 1m0s: 398 OK, 6 ignored, 8 failed, 3 skipped"
+	if actual := summarize(415, counts, time.Minute); actual != expected {
+		t.Errorf("expected summary '%s', got '%s'", expected, actual)
+	}
+	counts[StatusWarning] = 2
+	expected = "Checked 417 links in 1m0s: 398 OK, 6 ignored, 8 failed, 3 skipped, 2 warnings"
+	if actual := summarize(417, counts, time.Minute); actual != expected {
+		t.Errorf("expected summary '%s', got '%s'", expected, actual)
+	}
+}
+
+func TestCrawlNoGoroutineLeak(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a><a href="/missing">m</a>`)
+		case "/a", "/b":
+			fmt.Fprint(w, `<a href="/">home</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	site := mustParse(srv.URL + "/")
+	opts := DefaultCrawlOptions()
+
+	// warm up once, so that goroutines started lazily by the runtime or the
+	// test server are not counted as leaked
+	CrawlPageFunc(site, opts, func(*Result) {})
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		CrawlPageFunc(site, opts, func(*Result) {})
+	}
+
+	// connection goroutines need a moment to wind down
+	deadline := time.Now().Add(2 * time.Second)
+	after := runtime.NumGoroutine()
+	for after > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	if after > before {
+		t.Errorf("expected at most %d goroutines after crawling, got %d", before, after)
+	}
+}
+
+func TestCrawlAllSources(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a><a href="/missing">m</a>`)
+		case "/a", "/b":
+			fmt.Fprint(w, `<a href="/missing#top">m</a><a href="/">home</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.AllSources = true
+
+	var results []*Result
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		results = append(results, r)
+	})
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	last := results[len(results)-1]
+	if last.Status() != StatusFailed {
+		t.Fatalf("expected failed link to be reported last, got %v", last)
+	}
+	var sources []string
+	for _, source := range last.Sources {
+		sources = append(sources, source.Path)
+	}
+	sort.Strings(sources)
+	if expected := []string{"/", "/a", "/b"}; !reflect.DeepEqual(sources, expected) {
+		t.Errorf("expected sources %v, got %v", expected, sources)
+	}
+}
+
+func TestCrawlPages(t *testing.T) {
+	var other *httptest.Server
+	hits := make(map[string]int)
+	var mu sync.Mutex
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits[name+r.URL.Path]++
+			mu.Unlock()
+			switch r.URL.Path {
+			case "/":
+				fmt.Fprintf(w, `<a href="/page">page</a><a href="%s/">other</a>`, other.URL)
+			case "/page":
+				fmt.Fprint(w, `<a href="/">home</a>`)
+			default:
+				http.NotFound(w, r)
+			}
+		}
+	}
+	first := httptest.NewServer(handler("first"))
+	defer first.Close()
+	// Use another host name for the second site, so that it's not internal
+	// to the first one by accident.
+	other = httptest.NewServer(handler("other"))
+	defer other.Close()
+	other.URL = strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	opts := DefaultCrawlOptions()
+	opts.ReportOK = true
+	opts.Summary = false
+	opts.Formatter = NewTextFormatter(&bytes.Buffer{})
+	sites := []*url.URL{mustParse(first.URL + "/"), mustParse(other.URL + "/")}
+	if failed := CrawlPages(sites, opts); failed != 0 {
+		t.Errorf("expected no failed links, got %d", failed)
+	}
+	for _, page := range []string{"first/", "first/page", "other/", "other/page"} {
+		if hits[page] != 1 {
+			t.Errorf("expected page %s to be fetched once, got %d", page, hits[page])
+		}
+	}
+}
+
+func TestOKStatusCodes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			external := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="/members/">members</a><a href="%s/profile">profile</a>`, external)
+		case "/members/":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/profile":
+			w.WriteHeader(999)
+		}
+	}))
+	defer srv.Close()
+	site := mustParse(srv.URL + "/")
+
+	opts := DefaultCrawlOptions()
+	failed := make(map[string]int)
+	CrawlPageFunc(site, opts, func(r *Result) {
+		if r.Status() == StatusFailed {
+			failed[r.Link.URL.Path] = r.StatusCode
+		}
+	})
+	expected := map[string]int{"/members/": 401, "/profile": 999}
+	if !reflect.DeepEqual(failed, expected) {
+		t.Errorf("expected failed links %v, got %v", expected, failed)
+	}
+
+	opts.OKStatusCodes = []int{401, 999}
+	ok := make(map[string]int)
+	CrawlPageFunc(site, opts, func(r *Result) {
+		if r.Status() == StatusOK {
+			ok[r.Link.URL.Path] = r.StatusCode
+		}
+	})
+	expected["/"] = 200
+	if !reflect.DeepEqual(ok, expected) {
+		t.Errorf("expected succeeded links %v, got %v", expected, ok)
+	}
+}
+
+func TestIgnoreAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			external := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="/members/">members</a><a href="%s/admin">admin</a><a href="/gone">gone</a>`, external)
+		case "/members/":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/admin":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.IgnoreAuth = true
+	statuses := make(map[string]Status)
+	var reasons []string
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		statuses[r.Link.URL.Path] = r.Status()
+		if r.Status() == StatusIgnored {
+			reasons = append(reasons, r.Err.Error())
+		}
+	})
+	expected := map[string]Status{
+		"/":         StatusOK,
+		"/members/": StatusIgnored,
+		"/admin":    StatusIgnored,
+		"/gone":     StatusFailed,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected statuses %v, got %v", expected, statuses)
+	}
+	for _, reason := range reasons {
+		if !strings.Contains(reason, "authentication required") {
+			t.Errorf("expected reason to mention authentication, got %q", reason)
+		}
+	}
+}
+
+var matchesDomainTests = []struct {
+	host    string
+	domains []string
+	matches bool
+}{
+	{"example.com", nil, false},
+	{"example.com", []string{"example.com"}, true},
+	{"Example.COM", []string{"example.com"}, true},
+	{"www.example.com", []string{"example.com"}, false},
+	{"www.example.com", []string{"*.example.com"}, true},
+	{"a.b.example.com", []string{"*.example.com"}, true},
+	{"example.com", []string{"*.example.com"}, false},
+	{"badexample.com", []string{"*.example.com"}, false},
+	{"localhost", []string{"example.com", "localhost"}, true},
+}
+
+func TestMatchesDomain(t *testing.T) {
+	for _, testCase := range matchesDomainTests {
+		if actual := matchesDomain(testCase.host, testCase.domains); actual != testCase.matches {
+			t.Errorf("expected matchesDomain(%q, %v) to be %v, was %v",
+				testCase.host, testCase.domains, testCase.matches, actual)
+		}
+	}
+}
+
+func TestSkipDomains(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			external := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="%s/external">external</a>`, external)
+			return
+		}
+		hits++
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.SkipDomains = []string{"localhost"}
+
+	var ignored []string
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusIgnored {
+			ignored = append(ignored, r.Link.URL.Path)
+		}
+	})
+	if hits != 0 {
+		t.Errorf("expected skipped domain not to be requested, got %d requests", hits)
+	}
+	if !isEqual(ignored, []string{"/external"}) {
+		t.Errorf("expected /external to be ignored, got %v", ignored)
+	}
+}
+
+var hostKeyTests = []struct {
+	url          string
+	comparePorts bool
+	key          string
+}{
+	{"http://example.com/", false, "example.com"},
+	{"http://example.com:8080/", false, "example.com"},
+	{"http://example.com/", true, "example.com:"},
+	{"http://example.com:80/", true, "example.com:"},
+	{"https://example.com:443/", true, "example.com:"},
+	{"http://example.com:443/", true, "example.com:443"},
+	{"http://example.com:8080/", true, "example.com:8080"},
+	{"http://[::1]/", false, "::1"},
+	{"http://[::1]:8080/", false, "::1"},
+	{"http://[::1]/", true, "[::1]:"},
+	{"http://[::1]:8080/", true, "[::1]:8080"},
+}
+
+func TestHostKey(t *testing.T) {
+	for _, testCase := range hostKeyTests {
+		if key := hostKey(mustParse(testCase.url), testCase.comparePorts); key != testCase.key {
+			t.Errorf("expected hostKey(%s, %v) to be %q, was %q", testCase.url, testCase.comparePorts, testCase.key, key)
+		}
+	}
+}
+
+var isInternalPortTests = []struct {
+	link, site   string
+	comparePorts bool
+	internal     bool
+}{
+	{"http://host:8080/", "http://host/", false, true},
+	{"http://host:8080/", "http://host/", true, false},
+	{"http://host:80/", "http://host/", true, true},
+	{"https://host/", "https://host:443/", true, true},
+	{"https://host:443/", "http://host/", true, true},
+	{"http://host:8443/", "https://host:8443/", true, true},
+	{"http://[::1]:8080/", "http://[::1]/", false, true},
+	{"http://[::1]:8080/", "http://[::1]/", true, false},
+	{"http://[::1]:8080/", "http://[::1]:8080/", true, true},
+	{"http://[::2]/", "http://[::1]/", false, false},
+	{"/page", "http://[::1]:8080/", true, true},
+}
+
+func TestIsInternalPorts(t *testing.T) {
+	for _, testCase := range isInternalPortTests {
+		l := &Link{URL: mustParse(testCase.link), Orig: mustParse(testCase.site), comparePorts: testCase.comparePorts}
+		if internal := l.IsInternal(); internal != testCase.internal {
+			t.Errorf("expected %s from %s (compare ports: %v) to be internal: %v, was %v",
+				testCase.link, testCase.site, testCase.comparePorts, testCase.internal, internal)
+		}
+	}
+}
+
+func TestComparePorts(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		fmt.Fprint(w, `<a href="/deep">deep</a>`)
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s/page">other port</a>`, other.URL)
+	}))
+	defer srv.Close()
+
+	for _, comparePorts := range []bool{false, true} {
+		hits = make(map[string]int)
+		opts := DefaultCrawlOptions()
+		opts.ComparePorts = comparePorts
+		if failed := CrawlPageWithOptions(mustParse(srv.URL+"/"), opts); failed != 0 {
+			t.Errorf("expected no failed links (compare ports: %v), got %d", comparePorts, failed)
+		}
+		expected := map[string]int{"/page": 1, "/deep": 1}
+		if comparePorts {
+			expected = map[string]int{"/page": 1}
+		}
+		if !reflect.DeepEqual(hits, expected) {
+			t.Errorf("expected requests %v to the other port (compare ports: %v), got %v", expected, comparePorts, hits)
+		}
+	}
+}
+
+var isInternalHostTests = []struct {
+	link     string
+	internal bool
+}{
+	{"https://example.com/page", true},
+	{"https://static.example.com/logo.png", true},
+	{"https://STATIC.example.com/logo.png", true},
+	{"https://img.cdn.example.net/logo.png", true},
+	{"https://cdn.example.net/logo.png", false},
+	{"https://www.example.com/", false},
+	{"https://example.org/", false},
+}
+
+func TestIsInternalHosts(t *testing.T) {
+	site := mustParse("https://example.com/")
+	internalHosts := []string{"static.example.com", "*.cdn.example.net"}
+	for _, testCase := range isInternalHostTests {
+		l := &Link{URL: mustParse(testCase.link), Orig: site, internalHosts: internalHosts}
+		if internal := l.IsInternal(); internal != testCase.internal {
+			t.Errorf("expected %s to be internal: %v, was %v", testCase.link, testCase.internal, internal)
+		}
+	}
+}
+
+func TestCrawlInternalHosts(t *testing.T) {
+	var mu sync.Mutex
+	var hits []string
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		fmt.Fprint(w, `<a href="/deep">deep</a>`)
+	}))
+	defer cdn.Close()
+	cdnURL := strings.Replace(cdn.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s/page">page</a><img src="%s/logo.png">`, cdnURL, cdnURL)
+	}))
+	defer srv.Close()
+
+	for _, internalHosts := range [][]string{nil, {"localhost"}} {
+		hits = nil
+		opts := DefaultCrawlOptions()
+		opts.Resources = ResourceAttributes
+		opts.InternalOnly = true
+		opts.InternalHosts = internalHosts
+		if failed := CrawlPageWithOptions(mustParse(srv.URL+"/"), opts); failed != 0 {
+			t.Errorf("expected no failed links (internal hosts: %v), got %d", internalHosts, failed)
+		}
+		var expected []string
+		if len(internalHosts) > 0 {
+			expected = []string{"GET /deep", "GET /page", "HEAD /logo.png"}
+		}
+		sort.Strings(hits)
+		if !isEqual(hits, expected) {
+			t.Errorf("expected requests %v (internal hosts: %v), got %v", expected, internalHosts, hits)
+		}
+	}
+}
+
+var registrableDomainTests = []struct {
+	url, domain string
+}{
+	{"https://example.com/", "example.com"},
+	{"https://www.example.com/", "example.com"},
+	{"https://blog.Example.co.uk:8443/", "example.co.uk"},
+	{"https://example.co.uk./", "example.co.uk"},
+	{"https://co.uk/", ""},
+	{"http://localhost:8080/", ""},
+	{"http://127.0.0.1:8080/", ""},
+	{"http://[::1]/", ""},
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	for _, testCase := range registrableDomainTests {
+		if domain := registrableDomain(mustParse(testCase.url)); domain != testCase.domain {
+			t.Errorf("expected registrable domain of %s to be %q, got %q", testCase.url, testCase.domain, domain)
+		}
+	}
+}
+
+func TestIncludeSubdomains(t *testing.T) {
+	var mu sync.Mutex
+	var hits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, r.Method+" "+r.Host+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			_, port, _ := net.SplitHostPort(r.Host)
+			fmt.Fprintf(w, `<a href="http://blog.example.co.uk:%s/">blog</a>`, port)
+			fmt.Fprintf(w, `<a href="http://other.co.uk:%s/">other</a>`, port)
+		}
+	}))
+	defer srv.Close()
+	// Every host name is resolved to the test server.
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+	}}
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+
+	for _, includeSubdomains := range []bool{false, true} {
+		hits = nil
+		opts := DefaultCrawlOptions()
+		opts.Client = client
+		opts.IncludeSubdomains = includeSubdomains
+		CrawlPageFunc(mustParse("http://example.co.uk:"+port+"/"), opts, func(*Result) {})
+		expected := []string{"GET example.co.uk:%s/", "HEAD blog.example.co.uk:%s/", "HEAD other.co.uk:%s/"}
+		if includeSubdomains {
+			expected = []string{"GET blog.example.co.uk:%s/", "GET example.co.uk:%s/", "HEAD other.co.uk:%s/"}
+		}
+		for i := range expected {
+			expected[i] = fmt.Sprintf(expected[i], port)
+		}
+		sort.Strings(hits)
+		if !isEqual(hits, expected) {
+			t.Errorf("expected requests %v (include subdomains: %t), got %v", expected, includeSubdomains, hits)
+		}
+	}
+}
+
+func TestIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<a href="/a">a</a><a href="http://%s/b">b</a>`, r.Host)
+		case "/a", "/b":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	srv.Listener.Close()
+	srv.Listener = listener
+	srv.Start()
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.ComparePorts = true
+	internal := make(map[string]bool)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() != StatusOK {
+			t.Errorf("expected %s to be OK, got %v", r.Link.URL, r.Err)
+		}
+		internal[r.Link.URL.String()] = r.Link.IsInternal()
+	})
+	expected := map[string]bool{srv.URL + "/": true, srv.URL + "/a": true, srv.URL + "/b": true}
+	if !reflect.DeepEqual(internal, expected) {
+		t.Errorf("expected links %v, got %v", expected, internal)
+	}
+}
+
+func TestInternalOnly(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.Host+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			external := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="%s/external">external</a><a href="/internal">internal</a>`, external)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.InternalOnly = true
+
+	statuses := make(map[string]Status)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		statuses[r.Link.URL.Path] = r.Status()
+	})
+	expected := map[string]Status{"/": StatusOK, "/internal": StatusOK, "/external": StatusIgnored}
+	if fmt.Sprint(statuses) != fmt.Sprint(expected) {
+		t.Errorf("expected statuses %v, got %v", expected, statuses)
+	}
+	for _, request := range requested {
+		if strings.HasPrefix(request, "localhost") {
+			t.Errorf("expected external link not to be requested, got request for %s", request)
+		}
+	}
+}
+
+func TestFetchDocumentCompressed(t *testing.T) {
+	const page = `<a href="/compressed">compressed</a>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), r.URL.Path[1:]) {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Encoding", r.URL.Path[1:])
+		var compressor io.WriteCloser
+		switch r.URL.Path {
+		case "/gzip":
+			compressor = gzip.NewWriter(w)
+		case "/deflate":
+			compressor = zlib.NewWriter(w)
+		default:
+			fmt.Fprint(w, "not compressed")
+			return
+		}
+		fmt.Fprint(compressor, page)
+		compressor.Close()
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		doc, err := FetchDocument(srv.URL+"/"+encoding, srv.Client(), &opts)
+		if err != nil {
+			t.Errorf("fetch %s document: %v", encoding, err)
+			continue
+		}
+		if hrefs := ExtractTagAttribute(doc, "a", "href"); !isEqual(hrefs, []string{"/compressed"}) {
+			t.Errorf("expected links of %s document to be extracted, got %v", encoding, hrefs)
+		}
+	}
+
+	_, err := FetchDocument(srv.URL+"/br", srv.Client(), &opts)
+	if err == nil || !strings.Contains(err.Error(), "406") {
+		t.Errorf("expected unsupported encoding not to be accepted, got %v", err)
+	}
+	opts.Headers = http.Header{"Accept-Encoding": {"br"}}
+	_, err = FetchDocument(srv.URL+"/br", srv.Client(), &opts)
+	if err == nil || !strings.Contains(err.Error(), `unsupported content encoding "br"`) {
+		t.Errorf("expected error for unsupported encoding, got %v", err)
+	}
+}
+
+func TestFetchDocumentDefaultOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s">agent</a>`, r.UserAgent())
+	}))
+	defer srv.Close()
+	doc, err := FetchDocument(srv.URL, srv.Client(), nil)
+	if err != nil {
+		t.Fatalf("fetch document without options: %v", err)
+	}
+	if hrefs := ExtractTagAttribute(doc, "a", "href"); !isEqual(hrefs, []string{UserAgent}) {
+		t.Errorf("expected the default User-Agent to be sent, got %v", hrefs)
+	}
+}
+
+func TestNilOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, sitemapAbout, "http://"+r.Host)
+		default:
+			fmt.Fprint(w, `<a href="/about/">About</a>`)
+		}
+	}))
+	defer srv.Close()
+	if _, err := FetchDocument(srv.URL, srv.Client(), nil); err != nil {
+		t.Errorf("fetch document with nil options: %v", err)
+	}
+	if _, err := FetchDocumentContext(context.Background(), srv.URL, srv.Client(), nil); err != nil {
+		t.Errorf("fetch document with context and nil options: %v", err)
+	}
+	links, err := LinksFromSitemap(srv.URL+"/sitemap.xml", srv.Client(), nil)
+	if err != nil {
+		t.Fatalf("links from sitemap with nil options: %v", err)
+	}
+	if len(links) != 1 || links[0].URL.String() != srv.URL+"/about/" {
+		t.Errorf("expected link to %s/about/, got %v", srv.URL, links)
+	}
+}
+
+func TestFetchDocumentCorrupt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		fmt.Fprint(w, "not gzipped at all")
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	_, err := FetchDocument(srv.URL, srv.Client(), &opts)
+	if err == nil || !strings.Contains(err.Error(), "decompress gzip response") {
+		t.Errorf("expected decompression error, got %v", err)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a/">a</a>`)
+		case "/a/":
+			fmt.Fprint(w, `<a href="b/">b</a>`)
+		case "/a/b/":
+			fmt.Fprint(w, `<a href="/missing">missing</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	var buf bytes.Buffer
+	formatter := NewTextFormatter(&buf)
+	formatter.Trace = true
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	opts.Formatter = formatter
+
+	CrawlPageWithOptions(mustParse(srv.URL+"/"), opts)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a result and a trace line, got %q", buf.String())
+	}
+	expected := fmt.Sprintf(`	found at: "%[1]s/" > "%[1]s/a/" > "%[1]s/a/b/" > "%[1]s/missing"`, srv.URL)
+	if lines[1] != expected {
+		t.Errorf("expected trace\n%s\ngot\n%s", expected, lines[1])
+	}
+}
+
+func TestOutput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/missing">missing</a>`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	var buf bytes.Buffer
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	opts.Output = &buf
+
+	if failed := CrawlPageWithOptions(mustParse(srv.URL+"/"), opts); failed != 1 {
+		t.Errorf("expected 1 failed link, got %d", failed)
+	}
+	expected := fmt.Sprintf(`FAIL "%[1]s/missing": from "%[1]s/" GET 404 Not Found %[1]s/missing`+"\n", srv.URL)
+	if buf.String() != expected {
+		t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestHideWarnings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/missing">missing</a><a href="/ok">ok</a><a href="/ok">ok</a>`)
+			return
+		} else if r.URL.Path == "/ok" {
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	opts.ReportDuplicates = true
+
+	for _, hide := range []bool{false, true} {
+		var buf bytes.Buffer
+		opts.Output = &buf
+		opts.HideWarnings = hide
+		CrawlPageWithOptions(mustParse(srv.URL+"/"), opts)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		sort.Strings(lines)
+		expected := []string{fmt.Sprintf(`FAIL "%[1]s/missing": from "%[1]s/" GET 404 Not Found %[1]s/missing`, srv.URL)}
+		if !hide {
+			expected = append(expected, fmt.Sprintf(`WARN "%[1]s/ok": from "%[1]s/" warning: linked 2 times on the page`, srv.URL))
+		}
+		if !isEqual(lines, expected) {
+			t.Errorf("expected output %v (hide warnings: %v), got %v", expected, hide, lines)
+		}
+	}
+}
+
+func TestMaxLinks(t *testing.T) {
+	var mu sync.Mutex
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, `<a href="%s/%d">%d</a>`, strings.TrimSuffix(r.URL.Path, "/"), i, i)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.MaxLinks = 25
+
+	statuses := make(map[Status]int)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		statuses[r.Status()]++
+	})
+	if statuses[StatusOK] != opts.MaxLinks || hits != opts.MaxLinks {
+		t.Errorf("expected %d links to be checked, got %d results and %d requests",
+			opts.MaxLinks, statuses[StatusOK], hits)
+	}
+	if statuses[StatusSkipped] == 0 {
+		t.Errorf("expected links beyond the limit to be reported as skipped")
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	var mu sync.Mutex
+	var hits int
+	// Every page links to ten further pages, so that the crawl never ends
+	// unless it's stopped, and the pages ending in 3 are missing.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "3") {
+			http.NotFound(w, r)
+			return
+		}
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, `<a href="%s/%d">%d</a>`, strings.TrimSuffix(r.URL.Path, "/"), i, i)
+		}
+	}))
+	defer srv.Close()
+
+	for _, allSources := range []bool{false, true} {
+		mu.Lock()
+		hits = 0
+		mu.Unlock()
+		opts := DefaultCrawlOptions()
+		opts.FailFast = true
+		opts.AllSources = allSources
+		statuses := make(map[Status]int)
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			statuses[r.Status()]++
+			if r.Status() == StatusSkipped && !strings.Contains(r.Err.Error(), errFailFast.Error()) {
+				t.Errorf("expected link to be skipped after the first failure, got %v", r.Err)
+			}
+		})
+		if statuses[StatusFailed] == 0 || statuses[StatusSkipped] == 0 {
+			t.Errorf("expected failed and skipped links (all sources: %t), got %v", allSources, statuses)
+		}
+		mu.Lock()
+		if limit := 10 * Parallelism; hits > limit {
+			t.Errorf("expected the crawl to stop early (all sources: %t), got %d requests", allSources, hits)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestCheckMixedContent(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer plain.Close()
+	plain.URL = strings.Replace(plain.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<script src="%[1]s/app.js"></script>
+			<script src="/local.js"></script>
+			<a href="%[1]s/page.html">page</a>`, plain.URL)
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Insecure = true
+	opts.Resources = ResourceAttributes
+
+	for _, check := range []bool{false, true} {
+		opts.CheckMixedContent = check
+		var warnings []string
+		statuses := make(map[Status]int)
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			statuses[r.Status()]++
+			if r.Status() == StatusWarning {
+				warnings = append(warnings, r.String())
+			}
+		})
+		var expected []string
+		if check {
+			expected = []string{fmt.Sprintf(`WARN "%s/app.js" <script>: from "%s" `+
+				`warning: mixed content (http resource on https page)`, plain.URL, srv.URL+"/")}
+		}
+		if !isEqual(warnings, expected) {
+			t.Errorf("expected warnings %v (check: %v), got %v", expected, check, warnings)
+		}
+		if statuses[StatusOK] != 4 {
+			t.Errorf("expected all 4 links to be checked, got %v", statuses)
+		}
+	}
+}
+
+func TestReportDuplicates(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/about">About</a>
+				<a href="about">About us</a>
+				<a href="/about#team">Team</a>
+				<a href="/contact">Contact</a>
+				<a href="#top">Top</a><a href="#top">Top</a>
+				<a href="/about">About</a>`)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.ReportDuplicates = true
+
+	var warnings []string
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusWarning {
+			warnings = append(warnings, r.String())
+		}
+	})
+	expected := []string{
+		fmt.Sprintf(`WARN "%s/about": from "%s/" warning: linked 3 times on the page`, srv.URL, srv.URL),
+		fmt.Sprintf(`WARN "%s/#top": from "%s/" warning: linked 2 times on the page`, srv.URL, srv.URL),
+	}
+	if !isEqual(warnings, expected) {
+		t.Errorf("expected warnings %v, got %v", expected, warnings)
+	}
+	if hits["/about"] != 1 {
+		t.Errorf("expected the duplicate link to be checked once, got %d requests", hits["/about"])
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	var auth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.String())
+		auth = r.Header.Get("Proxy-Authorization")
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/page.html">page</a>`)
+		}
+	}))
+	defer proxy.Close()
+	opts := DefaultCrawlOptions()
+	opts.Proxy = mustParse(proxy.URL)
+	opts.Proxy.User = url.UserPassword("user", "secret")
+
+	var failed int
+	CrawlPageFunc(mustParse("http://checklinks.invalid/"), opts, func(r *Result) {
+		if r.Status() == StatusFailed {
+			failed++
+		}
+	})
+	if failed != 0 {
+		t.Errorf("expected all links to be checked through the proxy, got %d failed", failed)
+	}
+	sort.Strings(requested)
+	expected := []string{"http://checklinks.invalid/", "http://checklinks.invalid/page.html"}
+	if !isEqual(requested, expected) {
+		t.Errorf("expected proxy requests %v, got %v", expected, requested)
+	}
+	if expected := "Basic dXNlcjpzZWNyZXQ="; auth != expected {
+		t.Errorf("expected Proxy-Authorization %q, got %q", expected, auth)
+	}
+}
+
+func TestExternalTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			external := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="/slow.pdf">internal</a><a href="%s/slow.pdf">external</a>`, external)
+		case "/slow.pdf":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Timeout = 100 * time.Millisecond
+	opts.ExternalTimeout = 2 * time.Second
+
+	failed := make(map[string]bool)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusFailed {
+			failed[r.Link.URL.Hostname()] = true
+		}
+	})
+	if expected := map[string]bool{"127.0.0.1": true}; !reflect.DeepEqual(failed, expected) {
+		t.Errorf("expected only the internal link to time out, got failures %v", failed)
+	}
+}
+
+func TestRetryTimeoutWithRange(t *testing.T) {
+	var mu sync.Mutex
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			external := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="%[1]s/video.mp4">video</a><a href="%[1]s/missing.mp4">missing</a>`, external)
+		case r.Method == http.MethodHead:
+			// The server hangs on HEAD requests until the client gives up.
+			<-r.Context().Done()
+		case r.URL.Path == "/video.mp4":
+			mu.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			mu.Unlock()
+			http.ServeContent(w, r, "video.mp4", time.Time{}, strings.NewReader("not really a video"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, retry := range []bool{false, true} {
+		mu.Lock()
+		ranges = nil
+		mu.Unlock()
+		opts := DefaultCrawlOptions()
+		opts.Timeout = 100 * time.Millisecond
+		opts.RetryTimeoutWithRange = retry
+		results := make(map[string]string)
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			results[r.Link.URL.Path] = fmt.Sprintf("%s %d %s", r.Status(), r.StatusCode, r.Kind())
+		})
+		expected := map[string]string{
+			"/":            "OK 200 none",
+			"/video.mp4":   "FAIL 0 timeout",
+			"/missing.mp4": "FAIL 0 timeout",
+		}
+		var expectedRanges []string
+		if retry {
+			expected["/video.mp4"] = "OK 206 none"
+			expected["/missing.mp4"] = "FAIL 404 status"
+			expectedRanges = []string{"bytes=0-0"}
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("expected results %v (retry: %t), got %v", expected, retry, results)
+		}
+		if !isEqual(ranges, expectedRanges) {
+			t.Errorf("expected ranges %v to be requested (retry: %t), got %v", expectedRanges, retry, ranges)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="/excluded.pdf">excluded</a><a href="/page.html">page</a>`)
+	}))
+	defer srv.Close()
+	for _, level := range []slog.Level{slog.LevelInfo, slog.LevelDebug} {
+		var buf bytes.Buffer
+		opts := DefaultCrawlOptions()
+		opts.Exclude = []*regexp.Regexp{regexp.MustCompile(`\.pdf$`)}
+		opts.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level}))
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(*Result) {})
+
+		logged := buf.String()
+		if !strings.Contains(logged, `msg="link not checked" url=`+srv.URL+`/excluded.pdf`) {
+			t.Errorf("expected excluded link to be logged at level %v, got\n%s", level, logged)
+		}
+		for _, msg := range []string{"link dispatched", "token acquired", "token released", "request sent", "response received"} {
+			if debug := strings.Contains(logged, `msg="`+msg+`"`); debug != (level == slog.LevelDebug) {
+				t.Errorf("expected %q to be logged only at the debug level (level %v), got\n%s", msg, level, logged)
+			}
+		}
+	}
+}
+
+func TestProtocolRelativeURLs(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			// Use another host name, so that the link is external.
+			host := strings.Replace(r.Host, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="//%s/app.js">app</a>`, host)
+		}
+	}
+	plain := httptest.NewServer(http.HandlerFunc(handler))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(handler))
+	defer secure.Close()
+	opts := DefaultCrawlOptions()
+	opts.Insecure = true
+
+	for _, srv := range []*httptest.Server{plain, secure} {
+		var checked []string
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			if r.Status() != StatusOK {
+				t.Errorf("expected %s to be checked successfully, got %v", r.Link.URL, r)
+			}
+			checked = append(checked, r.Link.URL.String())
+		})
+		sort.Strings(checked)
+		expected := []string{srv.URL + "/", strings.Replace(srv.URL, "127.0.0.1", "localhost", 1) + "/app.js"}
+		sort.Strings(expected)
+		if !isEqual(checked, expected) {
+			t.Errorf("expected %v to be checked, got %v", expected, checked)
+		}
+	}
+}
+
+func TestStripParams(t *testing.T) {
+	tests := []struct {
+		address  string
+		patterns []string
+		expected string
+	}{
+		{"https://example.com/?utm_source=a&utm_medium=b", []string{"utm_*"}, "https://example.com/"},
+		{"https://example.com/?id=1&utm_source=a&page=2", []string{"utm_*"}, "https://example.com/?id=1&page=2"},
+		{"https://example.com/?fbclid=x&id=1", []string{"utm_*", "fbclid"}, "https://example.com/?id=1"},
+		{"https://example.com/?id=1&page=2#top", []string{"*"}, "https://example.com/#top"},
+		{"https://example.com/?utm%5Fsource=a&utmost=1", []string{"utm_*"}, "https://example.com/?utmost=1"},
+		{"https://example.com/?ref=a&refs=b", []string{"ref"}, "https://example.com/?refs=b"},
+		{"https://example.com/?id=1", nil, "https://example.com/?id=1"},
+	}
+	for _, test := range tests {
+		actual := stripParams(mustParse(test.address), test.patterns).String()
+		if actual != test.expected {
+			t.Errorf("strip %v from %s: expected %s, got %s", test.patterns, test.address, test.expected, actual)
+		}
+	}
+}
+
+func TestCrawlStripParams(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.RequestURI())
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/page?utm_source=a">a</a><a href="/page?utm_source=b&fbclid=x">b</a>
+				<a href="/page?id=1&utm_campaign=c">c</a>`)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.StripParams = []string{"utm_*", "fbclid"}
+
+	var checked []string
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		checked = append(checked, r.Link.URL.RequestURI())
+	})
+	sort.Strings(checked)
+	expected := []string{"/", "/page?id=1&utm_campaign=c", "/page?utm_source=a"}
+	if !isEqual(checked, expected) {
+		t.Errorf("expected checked links %v, got %v", expected, checked)
+	}
+	sort.Strings(requested)
+	if !isEqual(requested, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requested)
+	}
+}
+
+func TestSizeLimitReader(t *testing.T) {
+	tests := []struct {
+		content  string
+		limit    int64
+		read     string
+		exceeded bool
+	}{
+		{"0123456789", 4, "0123", true},
+		{"0123456789", 10, "0123456789", false},
+		{"0123456789", 11, "0123456789", false},
+		{"0123456789", 0, "0123456789", false},
+		{"", 4, "", false},
+	}
+	for _, test := range tests {
+		r := newSizeLimitReader(strings.NewReader(test.content), test.limit)
+		read, err := io.ReadAll(r)
+		if err != nil {
+			t.Errorf("read %q limited to %d: %v", test.content, test.limit, err)
+		}
+		if string(read) != test.read || r.exceeded != test.exceeded {
+			t.Errorf("read %q limited to %d: expected %q (exceeded: %v), got %q (exceeded: %v)",
+				test.content, test.limit, test.read, test.exceeded, read, r.exceeded)
+		}
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(1<<20))
+		if r.Method == http.MethodGet {
+			w.Write(make([]byte, 1<<20))
+		}
+	}))
+	defer external.Close()
+	external.URL = strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<a href="/big">big</a><a href="%s/video.mp4">video</a>`, external.URL)
+		case "/big":
+			fmt.Fprintf(w, `<a href="/early">early</a>%s<a href="/late">late</a>`, strings.Repeat(" ", 1024))
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.MaxBodySize = 512
+
+	statuses := make(map[string]Status)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		statuses[r.Link.URL.Path] = r.Status()
+	})
+	expected := map[string]Status{"/": StatusOK, "/big": StatusWarning, "/early": StatusOK, "/video.mp4": StatusWarning}
+	if fmt.Sprint(statuses) != fmt.Sprint(expected) {
+		t.Errorf("expected statuses %v, got %v", expected, statuses)
+	}
+}
+
+func TestMatchesExtension(t *testing.T) {
+	extensions := []string{"pdf", ".ZIP"}
+	tests := []struct {
+		address string
+		matches bool
+	}{
+		{"https://example.com/manual.pdf", true},
+		{"https://example.com/manual.PDF?download=1", true},
+		{"https://example.com/archive.zip#files", true},
+		{"https://example.com/get?file=manual.pdf", false},
+		{"https://example.com/pdf", false},
+		{"https://example.com/docs.pdf/", false},
+		{"https://example.com/manual.pdf.html", false},
+		{"https://example.com/", false},
+	}
+	for _, test := range tests {
+		if actual := matchesExtension(mustParse(test.address), extensions); actual != test.matches {
+			t.Errorf("expected %s to match %v: %v, got %v", test.address, extensions, test.matches, actual)
+		}
+	}
+}
+
+func TestExtensionFilters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/docs/">docs</a><a href="/video.mp4">video</a><a href="/setup.zip?v=2">setup</a>`)
+		case "/docs/":
+			fmt.Fprint(w, `<a href="/manual.pdf">manual</a><a href="/page.html">page</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		skip, only []string
+		ignored    []string
+	}{
+		{nil, nil, nil},
+		{[]string{"mp4", "zip"}, nil, []string{"/setup.zip", "/video.mp4"}},
+		{nil, []string{"pdf"}, []string{"/setup.zip", "/video.mp4"}},
+		{[]string{"pdf"}, []string{"pdf"}, []string{"/manual.pdf", "/setup.zip", "/video.mp4"}},
+	}
+	for _, test := range tests {
+		opts := DefaultCrawlOptions()
+		opts.SkipExtensions = test.skip
+		opts.OnlyExtensions = test.only
+		var ignored []string
+		var checked int
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			if r.Status() == StatusIgnored {
+				ignored = append(ignored, r.Link.URL.Path)
+			} else {
+				checked++
+			}
+		})
+		sort.Strings(ignored)
+		if !isEqual(ignored, test.ignored) || checked+len(ignored) != 6 {
+			t.Errorf("skip %v, only %v: expected %v to be ignored, got %v (%d checked)",
+				test.skip, test.only, test.ignored, ignored, checked)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a>`)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Parallelism = 1
+	opts.Delay = 20 * time.Millisecond
+	opts.Jitter = 10 * time.Millisecond
+
+	start := time.Now()
+	var checked int
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusOK {
+			checked++
+		}
+	})
+	if elapsed := time.Since(start); checked != 3 || elapsed < 3*opts.Delay {
+		t.Errorf("expected 3 links checked in at least %v, got %d in %v", 3*opts.Delay, checked, elapsed)
+	}
+
+	opts.Delay = time.Hour
+	opts.MaxDuration = 50 * time.Millisecond
+	start = time.Now()
+	var skipped int
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusSkipped {
+			skipped++
+		}
+	})
+	if elapsed := time.Since(start); skipped != 1 || elapsed > time.Second {
+		t.Errorf("expected the delayed crawl to be aborted with 1 skipped link, got %d in %v", skipped, elapsed)
+	}
+}
+
+var checkContentTypeTests = []struct {
+	element, rel, contentType string
+	warning                   bool
+}{
+	{"img", "", "image/png", false},
+	{"img", "", "image/svg+xml", false},
+	{"img", "", "text/html; charset=utf-8", true},
+	{"img", "", "", false},
+	{"img", "", "image/png; =", true},
+	{"script", "", "text/javascript; charset=utf-8", false},
+	{"script", "", "application/javascript", false},
+	{"script", "", "text/plain", true},
+	{"link", "stylesheet", "text/css", false},
+	{"link", "stylesheet", "text/html", true},
+	{"link", "icon", "text/html", false},
+	{"a", "", "application/pdf", false},
+}
+
+func TestCheckContentType(t *testing.T) {
+	for _, testCase := range checkContentTypeTests {
+		l := &Link{URL: mustParse("https://example.com/file"), Element: testCase.element, Rel: testCase.rel}
+		err := checkContentType(l, testCase.contentType)
+		if warning := errors.Is(err, errWarning); warning != testCase.warning {
+			t.Errorf("expected <%s rel=%q> with content type %q to be a warning: %t, got %v",
+				testCase.element, testCase.rel, testCase.contentType, testCase.warning, err)
+		}
+	}
+}
+
+func TestCrawlContentType(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<img src="/logo.png"><img src="/missing.png"><a href="/manual.pdf">manual</a>`)
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/manual.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		default:
+			// An error page served with 200 OK.
+			fmt.Fprint(w, "<h1>Not Found</h1>")
+		}
+	}))
+	defer srv.Close()
+
+	for _, check := range []bool{false, true} {
+		opts := DefaultCrawlOptions()
+		opts.Resources = ResourceAttributes
+		opts.CheckContentType = check
+		contentTypes := make(map[string]string)
+		statuses := make(map[string]Status)
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			contentTypes[r.Link.URL.Path] = r.ContentType
+			statuses[r.Link.URL.Path] = r.Status()
+		})
+		expectedContentTypes := map[string]string{
+			"/":            "text/html; charset=utf-8",
+			"/logo.png":    "image/png",
+			"/missing.png": "text/html; charset=utf-8",
+			"/manual.pdf":  "application/pdf",
+		}
+		if !reflect.DeepEqual(contentTypes, expectedContentTypes) {
+			t.Errorf("expected content types %v, got %v", expectedContentTypes, contentTypes)
+		}
+		expectedStatus := StatusOK
+		if check {
+			expectedStatus = StatusWarning
+		}
+		if statuses["/missing.png"] != expectedStatus || statuses["/logo.png"] != StatusOK || statuses["/manual.pdf"] != StatusOK {
+			t.Errorf("expected only /missing.png to be %s (check: %t), got %v", expectedStatus, check, statuses)
+		}
+	}
+}
+
+func TestIsHTML(t *testing.T) {
+	for contentType, expected := range map[string]bool{
+		"":                          true,
+		"text/html":                 true,
+		"Text/HTML; charset=utf-8":  true,
+		"application/xhtml+xml":     true,
+		"text/html; charset":        true,
+		"application/json":          false,
+		"application/pdf":           false,
+		"text/plain; charset=utf-8": false,
+	} {
+		if actual := isHTML(contentType); actual != expected {
+			t.Errorf("expected content type %q to be HTML: %t, was %t", contentType, expected, actual)
+		}
+	}
+}
+
+func TestCrawlNonHTML(t *testing.T) {
+	var mu sync.Mutex
+	var hits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="/data.json">data</a>`)
+		case "/data.json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"html": "<a href=\"/nonsense\">nonsense</a>"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	results := make(map[string]*Result)
+	CrawlPageFunc(mustParse(srv.URL+"/"), DefaultCrawlOptions(), func(r *Result) {
+		results[r.Link.URL.Path] = r
+	})
+	if len(results) != 2 {
+		t.Errorf("expected results for / and /data.json only, got %v", results)
+	}
+	if r := results["/data.json"]; r == nil || r.Status() != StatusOK || r.ContentType != "application/json" {
+		t.Errorf("expected /data.json to be OK with content type application/json, got %+v", r)
+	}
+	sort.Strings(hits)
+	if expected := []string{"GET /", "GET /data.json"}; !isEqual(hits, expected) {
+		t.Errorf("expected requests %v, got %v", expected, hits)
+	}
+
+	opts := DefaultCrawlOptions()
+	_, err := FetchDocument(srv.URL+"/data.json", srv.Client(), &opts)
+	if err == nil || !strings.Contains(err.Error(), "content type application/json is not HTML") {
+		t.Errorf("expected error for non-HTML document, got %v", err)
+	}
+}
+
+func TestOtherFormatter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/ok">ok</a><a href="/missing">missing</a><a href="mailto:a@b.c">mail</a>`)
+		case "/ok":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var problems, others bytes.Buffer
+	opts := DefaultCrawlOptions()
+	opts.ReportOK = true
+	opts.ReportIgnored = true
+	opts.Summary = false
+	opts.Formatter = NewCSVFormatter(&problems)
+	opts.OtherFormatter = NewTextFormatter(&others)
+	if failed := CrawlPageWithOptions(mustParse(srv.URL+"/"), opts); failed != 1 {
+		t.Errorf("expected 1 failed link, got %d", failed)
+	}
+	lines := strings.Split(strings.TrimSpace(problems.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "/missing,FAIL,404") {
+		t.Errorf("expected CSV header and the failed link only, got %q", problems.String())
+	}
+	var statuses []string
+	for _, line := range strings.Split(strings.TrimSpace(others.String()), "\n") {
+		statuses = append(statuses, strings.Fields(line)[0])
+	}
+	sort.Strings(statuses)
+	if expected := []string{"IGNORE", "OK", "OK"}; !isEqual(statuses, expected) {
+		t.Errorf("expected the other results %v, got %q", expected, others.String())
+	}
+}
+
+func TestCrawlVisited(t *testing.T) {
+	var mu sync.Mutex
+	requested := make([]string, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b#top">b</a><a href="/c?utm_source=x">c</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.StripParams = []string{"utm_*"}
+	opts.Visited = map[string]bool{srv.URL + "/b": true, srv.URL + "/c": true}
+	crawler := NewCrawler(opts)
+	summary := crawler.Crawl(context.Background(), mustParse(srv.URL+"/"))
+
+	reported := make([]string, 0)
+	for _, result := range crawler.Results() {
+		reported = append(reported, result.Link.URL.Path)
+	}
+	sort.Strings(reported)
+	if expected := []string{"/", "/a"}; !isEqual(reported, expected) {
+		t.Errorf("expected results for %v, got %v", expected, reported)
+	}
+	mu.Lock()
+	sort.Strings(requested)
+	if expected := []string{"/", "/a"}; !isEqual(requested, expected) {
+		t.Errorf("expected requests for %v, got %v", expected, requested)
+	}
+	mu.Unlock()
+	expected := []string{srv.URL + "/", srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"}
+	if !isEqual(summary.Visited, expected) {
+		t.Errorf("expected visited %v, got %v", expected, summary.Visited)
+	}
+}
+
+func TestCheckURLs(t *testing.T) {
+	var mu sync.Mutex
+	requested := make([]string, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<a href="/linked">not crawled</a>`)
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	opts.Output = io.Discard
+	urls := []*url.URL{mustParse(srv.URL + "/"), mustParse(srv.URL + "/page"), mustParse(srv.URL + "/gone")}
+	summary := CheckURLsContext(context.Background(), urls, opts)
+	if summary.Total != 3 || summary.Count(StatusOK) != 2 || summary.Count(StatusFailed) != 1 {
+		t.Errorf("expected 2 OK and 1 failed URL, got %v", summary.Statuses)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(requested)
+	if expected := []string{"HEAD /", "HEAD /gone", "HEAD /page"}; !isEqual(requested, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requested)
+	}
+}
+
+func TestCleanAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		cleaned string
+		changed bool
+	}{
+		{"/about", "/about", false},
+		{"/about us", "/about us", false},
+		{"  /about", "/about", true},
+		{"/about\n", "/about", true},
+		{"\thttps://example.com/", "https://example.com/", true},
+		{"/docs/\n  intro", "/docs/  intro", true},
+		{"/a\tb\r\n", "/ab", true},
+		{"\x00/about\x1f", "/about", true},
+	}
+	for _, test := range tests {
+		cleaned, changed := cleanAddress(test.address)
+		if cleaned != test.cleaned || changed != test.changed {
+			t.Errorf("expected %q to be cleaned as %q (%t), got %q (%t)", test.address, test.cleaned, test.changed, cleaned, changed)
+		}
+	}
+}
+
+func TestCrawlWhitespaceLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, "<a href=\"/clean\">clean</a><a href=\"\n/about\">about</a>"+
+				"<a href=\"  /docs  \">docs</a><a href=\"/f\taq\">faq</a>"+
+				"<img srcset=\" /logo.png 1x,\n /logo@2x.png 2x \">")
+		case "/clean", "/about", "/docs", "/faq":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	var warnings []string
+	statuses := make(map[string]Status)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		if r.Status() == StatusWarning {
+			warnings = append(warnings, r.Err.Error())
+			return
+		}
+		statuses[r.Link.URL.Path] = r.Status()
+	})
+	sort.Strings(warnings)
+	expected := []string{
+		`warning: href "  /docs  " contains whitespace or control characters`,
+		`warning: href "/f\taq" contains whitespace or control characters`,
+		`warning: href "\n/about" contains whitespace or control characters`,
+	}
+	if !isEqual(warnings, expected) {
+		t.Errorf("expected warnings %v, got %v", expected, warnings)
+	}
+	for _, path := range []string{"/clean", "/about", "/docs", "/faq"} {
+		if statuses[path] != StatusOK {
+			t.Errorf("expected the cleaned link %s to be checked, got %v", path, statuses)
+		}
+	}
+}
+
+func TestSuggestHTTPS(t *testing.T) {
+	var mu sync.Mutex
+	var secureHits []string
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		secureHits = append(secureHits, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path != "/both" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer secure.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			external := strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)
+			fmt.Fprintf(w, `<a href="%[1]s/both">both</a><a href="%[1]s/http-only">http only</a>
+				<a href="/internal">internal</a>`, external)
+		}
+	}))
+	defer srv.Close()
+	// The test server's host also answers https on the same port.
+	client := &http.Client{Transport: &http.Transport{
+		DialTLSContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true}}
+			return dialer.DialContext(ctx, network, secure.Listener.Addr().String())
+		},
+	}}
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+
+	for _, suggest := range []bool{false, true} {
+		secureHits = nil
+		opts := DefaultCrawlOptions()
+		opts.Client = client
+		opts.SuggestHTTPS = suggest
+		results := make(map[string]*Result)
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			results[r.Link.URL.Path] = r
+		})
+		if r := results["/internal"]; r == nil || r.Status() != StatusOK {
+			t.Errorf("expected internal link to be OK (suggest: %t), got %v", suggest, r)
+		}
+		if r := results["/http-only"]; r == nil || r.Status() != StatusOK {
+			t.Errorf("expected link without https to be OK (suggest: %t), got %v", suggest, r)
+		}
+		r := results["/both"]
+		if !suggest {
+			if r == nil || r.Status() != StatusOK || len(secureHits) > 0 {
+				t.Errorf("expected no https requests without suggesting, got %v and %v", r, secureHits)
+			}
+			continue
+		}
+		expected := fmt.Sprintf("https://localhost:%s/both", port)
+		if r == nil || r.Status() != StatusWarning || !strings.Contains(r.Err.Error(), expected) {
+			t.Errorf("expected warning suggesting %s, got %v", expected, r)
+		}
+		sort.Strings(secureHits)
+		if expected := []string{"/both", "/http-only"}; !isEqual(secureHits, expected) {
+			t.Errorf("expected https requests for %v, got %v", expected, secureHits)
+		}
+	}
+}
+
+func TestReportInternalExternal(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer external.Close()
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprintf(w, `<a href="/missing">missing</a><a href="%s/gone">gone</a>`, externalURL)
+		} else {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	internal := make(map[string]bool)
+	CrawlPageFunc(mustParse(srv.URL+"/"), DefaultCrawlOptions(), func(r *Result) {
+		internal[r.Link.URL.Path] = r.Internal
+	})
+	if expected := map[string]bool{"/": true, "/missing": true, "/gone": false}; !reflect.DeepEqual(internal, expected) {
+		t.Errorf("expected results tagged %v, got %v", expected, internal)
+	}
+
+	tests := []struct {
+		hideInternal, hideExternal bool
+		expected                   []string
+	}{
+		{false, false, []string{`FAIL "` + srv.URL + `/missing": [internal]`, `FAIL "` + externalURL + `/gone": [external]`}},
+		{false, true, []string{`FAIL "` + srv.URL + `/missing": [internal]`}},
+		{true, false, []string{`FAIL "` + externalURL + `/gone": [external]`}},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		formatter := NewTextFormatter(&out)
+		formatter.Tag = true
+		opts := DefaultCrawlOptions()
+		opts.Summary = false
+		opts.Formatter = formatter
+		opts.HideInternal = test.hideInternal
+		opts.HideExternal = test.hideExternal
+		// The failed links are counted, whether reported or not.
+		if failed := CrawlPageWithOptions(mustParse(srv.URL+"/"), opts); failed != 2 {
+			t.Errorf("expected 2 failed links, got %d", failed)
+		}
+		var reported []string
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			if line != "" {
+				// Keep the status, URL, and tag of every line.
+				fields := strings.Fields(line)
+				reported = append(reported, fields[0]+" "+fields[1]+" "+fields[len(fields)-1])
+			}
+		}
+		sort.Strings(reported)
+		if !isEqual(reported, test.expected) {
+			t.Errorf("expected reported links %q (hide internal: %t, external: %t), got %q",
+				test.expected, test.hideInternal, test.hideExternal, reported)
+		}
+	}
+}
+
+const linkTextDocument = `
+<!DOCTYPE html>
+<html>
+	<body>
+		<p><a href="/report.pdf">Download
+			the <strong>PDF</strong></a></p>
+		<a href="/"><img src="/logo.png" alt="Company  logo"></a>
+		<a href="/empty"></a>
+		<map name="m"><area href="/area" alt="Area"></map>
+		<link rel="stylesheet" href="/style.css">
+	</body>
+</html>
+`
+
+func TestExtractLinkText(t *testing.T) {
+	root, _ := html.Parse(strings.NewReader(linkTextDocument))
+	actual := make(map[string]string)
+	for _, link := range ExtractLinks(root, mustParse("https://example.com/")) {
+		actual[link.Element+" "+link.URL.Path] = link.Text
+	}
+	expected := map[string]string{
+		"a /report.pdf":   "Download the PDF",
+		"a /":             "Company logo",
+		"img /logo.png":   "Company logo",
+		"a /empty":        "",
+		"area /area":      "Area",
+		"link /style.css": "",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected link texts %q, got %q", expected, actual)
+	}
+
+	result := &Result{
+		Err:  errors.New("broken"),
+		Link: &Link{URL: mustParse("https://example.com/report.pdf"), Orig: mustParse("https://example.com/"), Text: "Download the PDF"},
+	}
+	var text bytes.Buffer
+	formatter := NewTextFormatter(&text)
+	formatter.LinkText = true
+	formatter.Format(result)
+	if expected := "FAIL \"https://example.com/report.pdf\": from \"https://example.com/\" broken\n\ttext: \"Download the PDF\"\n"; text.String() != expected {
+		t.Errorf("expected text output %q, got %q", expected, text.String())
+	}
+	var jsonl bytes.Buffer
+	NewJSONLFormatter(&jsonl).Format(result)
+	if !strings.Contains(jsonl.String(), `"text":"Download the PDF"`) {
+		t.Errorf("expected link text in JSONL output, got %s", jsonl.String())
+	}
 }
@@ -0,0 +1,109 @@
+package checklinks
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Crawler crawls sites with the options it has been created with, and keeps
+// the state of its crawls: links checked by one crawl are not checked again
+// by the later ones (like the Done links of a resumed crawl), and the results
+// of all the crawls are collected. Unlike the Crawl functions, a Crawler
+// doesn't report the results using the Formatter, but returns them from
+// Results. Independent Crawlers can be used concurrently.
+type Crawler struct {
+	opts   CrawlOptions
+	client *http.Client
+
+	mu      sync.Mutex
+	visited map[string]bool
+	results []*Result
+	summary *CrawlSummary
+}
+
+// NewCrawler creates a Crawler using the given options. The Done option's
+// links are considered visited already.
+func NewCrawler(opts CrawlOptions) *Crawler {
+	visited := make(map[string]bool)
+	for u := range opts.Done {
+		visited[u] = true
+	}
+	return &Crawler{
+		opts:    opts,
+		client:  newClient(&opts),
+		visited: visited,
+		summary: newCrawlSummary(),
+	}
+}
+
+// Crawl crawls the given site's URL until done, or until the given context is
+// done, in which case the links not processed by then are reported as
+// skipped. The summary of the crawl's results is returned.
+func (c *Crawler) Crawl(ctx context.Context, site *url.URL) *CrawlSummary {
+	start := time.Now()
+	summary := newCrawlSummary()
+	opts := c.opts
+	opts.BasicAuth = opts.BasicAuth.forHost(site.Host)
+	opts.Done = c.done()
+	summary.Visited = crawl(ctx, c.client, []*Link{{URL: site, Orig: site}}, opts, func(result *Result) {
+		summary.add(result)
+		c.record(result)
+	})
+	summary.Elapsed = time.Since(start)
+	c.mu.Lock()
+	c.summary.Elapsed += summary.Elapsed
+	c.mu.Unlock()
+	return summary
+}
+
+// Results returns the results of all the crawls so far.
+func (c *Crawler) Results() []*Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := make([]*Result, len(c.results))
+	copy(results, c.results)
+	return results
+}
+
+// Summary returns the summary of the results of all the crawls so far.
+func (c *Crawler) Summary() CrawlSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary := *c.summary
+	summary.Statuses = maps.Clone(c.summary.Statuses)
+	summary.StatusCodes = maps.Clone(c.summary.StatusCodes)
+	summary.ErrorKinds = maps.Clone(c.summary.ErrorKinds)
+	summary.Failed = append([]string(nil), c.summary.Failed...)
+	summary.PageLinks = maps.Clone(c.summary.PageLinks)
+	summary.Visited = make([]string, 0, len(c.visited))
+	for key := range c.visited {
+		summary.Visited = append(summary.Visited, key)
+	}
+	sort.Strings(summary.Visited)
+	return summary
+}
+
+// done returns the visit keys of the links checked so far, which is a copy
+// to be passed to a crawl as its Done option.
+func (c *Crawler) done() map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.visited)
+}
+
+// record collects the given result, and marks its link as visited unless it
+// has been skipped, so that it's not checked again by a later crawl.
+func (c *Crawler) record(result *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, result)
+	c.summary.add(result)
+	if result.Status() != StatusSkipped {
+		c.visited[visitKey(stripParams(result.Link.URL, c.opts.StripParams))] = true
+	}
+}
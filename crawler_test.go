@@ -0,0 +1,77 @@
+package checklinks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCrawler(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	shared := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path != "/shared" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer shared.Close()
+	sharedURL := strings.Replace(shared.URL, "127.0.0.1", "localhost", 1)
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s/shared">shared</a><a href="%s/missing">missing</a>`, sharedURL, sharedURL)
+	}))
+	defer site.Close()
+
+	crawler := NewCrawler(DefaultCrawlOptions())
+	first := crawler.Crawl(context.Background(), mustParse(site.URL+"/first"))
+	second := crawler.Crawl(context.Background(), mustParse(site.URL+"/second"))
+	if first.Total != 3 || first.Count(StatusFailed) != 1 {
+		t.Errorf("expected 3 results with 1 failed for the first crawl, got %d with %d failed",
+			first.Total, first.Count(StatusFailed))
+	}
+	if second.Total != 1 {
+		t.Errorf("expected only the second page to be reported by the second crawl, got %d results", second.Total)
+	}
+	if hits["/shared"] != 1 || hits["/missing"] != 1 {
+		t.Errorf("expected the links to be checked once, got %v", hits)
+	}
+	if results := crawler.Results(); len(results) != 4 {
+		t.Errorf("expected 4 results of both crawls, got %d", len(results))
+	}
+	if summary := crawler.Summary(); summary.Total != 4 || summary.Count(StatusFailed) != 1 {
+		t.Errorf("expected 4 results with 1 failed in total, got %d with %d failed",
+			summary.Total, summary.Count(StatusFailed))
+	}
+}
+
+func TestCrawlersIndependent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	crawlers := make([]*Crawler, 4)
+	for i := range crawlers {
+		crawlers[i] = NewCrawler(DefaultCrawlOptions())
+		wg.Add(1)
+		go func(c *Crawler) {
+			defer wg.Done()
+			c.Crawl(context.Background(), mustParse(srv.URL+"/"))
+		}(crawlers[i])
+	}
+	wg.Wait()
+	for i, c := range crawlers {
+		if n := len(c.Results()); n != 3 {
+			t.Errorf("expected crawler %d to have 3 results, got %d", i, n)
+		}
+	}
+}
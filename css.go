@@ -0,0 +1,157 @@
+package checklinks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxStylesheetSize is the max. amount of bytes read from a stylesheet.
+const maxStylesheetSize = 4 << 20
+
+var (
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssURL     = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)\s]*))\s*\)`)
+)
+
+// ExtractCSSURLs returns the targets of all url(...) references in the given
+// CSS code, which may be unquoted or quoted with single or double quotes.
+// References within comments are ignored.
+func ExtractCSSURLs(css string) []string {
+	targets := make([]string, 0)
+	css = cssComment.ReplaceAllString(css, "")
+	for _, match := range cssURL.FindAllStringSubmatch(css, -1) {
+		target := strings.TrimSpace(match[1] + match[2] + match[3])
+		if target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// extractStylesheets returns the href attributes of all <link> elements with
+// rel="stylesheet" in the given node's tree.
+func extractStylesheets(node *html.Node) []string {
+	hrefs := make([]string, 0)
+	for _, element := range findElements(node, "link") {
+		link := Link{Element: "link", Rel: attribute(element, "rel")}
+		if href := attribute(element, "href"); href != "" && link.IsStylesheet() {
+			hrefs = append(hrefs, href)
+		}
+	}
+	return hrefs
+}
+
+// extractStyles returns the CSS code of all <style> elements and style
+// attributes in the given node's tree.
+func extractStyles(node *html.Node) []string {
+	styles := make([]string, 0)
+	if node.Type == html.ElementNode {
+		if style := attribute(node, "style"); style != "" {
+			styles = append(styles, style)
+		}
+		if node.Data == "style" {
+			var code strings.Builder
+			for c := node.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.TextNode {
+					code.WriteString(c.Data)
+				}
+			}
+			styles = append(styles, code.String())
+		}
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		styles = append(styles, extractStyles(c)...)
+	}
+	return styles
+}
+
+// attribute returns the value of the given node's attribute with the given
+// name, or an empty string if there is no such attribute.
+func attribute(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// ProcessStylesheet uses the given http.Client and options to fetch the
+// stylesheet indicated by the given link, and reports the url() references
+// found in it, resolved relative to the stylesheet's URL. The link is reported
+// as skipped if the given context is done before it has been fetched. A
+// message is sent to the given done channel when the stylesheet has been
+// processed.
+func ProcessStylesheet(ctx context.Context, c *http.Client, opts *CrawlOptions, l *Link, links linkSink, res resSink, done doneSink, t chan struct{}) {
+	defer func() {
+		done <- struct{}{}
+	}()
+	if err := acquire(ctx, opts, l, t); err != nil {
+		res <- &Result{Err: err, Link: l}
+		return
+	}
+	requestCtx, cancel := requestContext(ctx, opts, l)
+	css, redirect, err := fetchStylesheet(requestCtx, l.URL.String(), c, opts)
+	cancel()
+	release(opts, l, t)
+	if err != nil && ctx.Err() != nil {
+		err = skipError(ctx)
+	}
+	if err != nil {
+		code := statusCode(err)
+		if opts.acceptsStatus(code) {
+			err = nil
+		}
+		res <- &Result{Err: opts.ignoreAuth(err), Link: l, StatusCode: code}
+		return
+	}
+	for _, ref := range ExtractCSSURLs(css) {
+		link, err := NewLink(ref, l.URL)
+		if err != nil {
+			res <- &Result{Err: fmt.Errorf("malformed link: %w", err), Link: l}
+			continue
+		}
+		link.Element = "css"
+		link.Parent = l
+		if scheme := ClassifyScheme(link.URL); scheme != SchemeHTTP {
+			res <- &Result{Err: newSchemeError(link.URL), Link: link, Scheme: scheme}
+			continue
+		}
+		link.URL = l.URL.ResolveReference(link.URL)
+		if opts.CheckMixedContent && isMixedContent(l.URL, link) {
+			res <- &Result{Err: errMixedContent, Link: link}
+		}
+		links <- link
+	}
+	res <- withRedirect(&Result{Err: nil, Link: l, StatusCode: http.StatusOK}, redirect, opts)
+}
+
+func fetchStylesheet(ctx context.Context, url string, c *http.Client, opts *CrawlOptions) (string, *Redirect, error) {
+	request, err := newGetRequest(ctx, url, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	response, err := doRequest(c, request)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", nil, &statusError{http.MethodGet, response.StatusCode, url}
+	}
+	body, err := decodeBody(response)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	css, err := io.ReadAll(io.LimitReader(body, maxStylesheetSize))
+	if err != nil {
+		return "", nil, fmt.Errorf("read stylesheet at %s: %v", url, err)
+	}
+	return string(css), redirectOf(response), nil
+}
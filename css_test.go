@@ -0,0 +1,109 @@
+package checklinks
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const stylesheet = `
+/* background: url(commented-out.png); */
+body {
+	background: URL( "img/background.png" );
+}
+@font-face {
+	src: url('/fonts/cheese.woff2') format("woff2"), url(/fonts/cheese.woff);
+}
+.empty { background: url(); }
+.inline { background: url(data:image/png;base64,iVBORw0KGgo=); }
+`
+
+var cssURLs = []string{
+	"img/background.png",
+	"/fonts/cheese.woff2",
+	"/fonts/cheese.woff",
+	"data:image/png;base64,iVBORw0KGgo=",
+}
+
+func TestExtractCSSURLs(t *testing.T) {
+	actual := ExtractCSSURLs(stylesheet)
+	if !isEqual(actual, cssURLs) {
+		t.Errorf("expected CSS URLs %v, got %v", cssURLs, actual)
+	}
+}
+
+const styledDocument = `
+<!DOCTYPE html>
+<html>
+	<head>
+		<link rel="stylesheet" href="/css/site.css">
+		<link rel="icon" href="/favicon.ico">
+		<link rel="alternate stylesheet" href="/css/dark.css">
+		<style>h1 { background: url(/img/title.png); }</style>
+	</head>
+	<body>
+		<div style="background-image: url('/img/cheese.jpg')">Cheese</div>
+	</body>
+</html>
+`
+
+func TestExtractStylesheets(t *testing.T) {
+	root, _ := html.Parse(bytes.NewBufferString(styledDocument))
+	expected := []string{"/css/site.css", "/css/dark.css"}
+	if actual := extractStylesheets(root); !isEqual(actual, expected) {
+		t.Errorf("expected stylesheets %v, got %v", expected, actual)
+	}
+}
+
+func TestExtractStyles(t *testing.T) {
+	root, _ := html.Parse(bytes.NewBufferString(styledDocument))
+	expected := []string{"/img/title.png", "/img/cheese.jpg"}
+	actual := make([]string, 0)
+	for _, style := range extractStyles(root) {
+		actual = append(actual, ExtractCSSURLs(style)...)
+	}
+	if !isEqual(actual, expected) {
+		t.Errorf("expected CSS URLs %v, got %v", expected, actual)
+	}
+}
+
+func TestCrawlCheckCSS(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, styledDocument)
+		case "/css/site.css":
+			fmt.Fprint(w, stylesheet)
+		case "/css/img/background.png", "/fonts/cheese.woff2", "/img/title.png":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	pageURL, _ := url.Parse(srv.URL)
+	opts := DefaultCrawlOptions()
+	opts.CheckCSS = true
+	opts.ReportFailed = false
+	failed := CrawlPageWithOptions(pageURL, opts)
+
+	// dark.css, cheese.woff, and cheese.jpg are missing
+	if failed != 3 {
+		t.Errorf("expected 3 failed links, got %d", failed)
+	}
+	if hits["/css/img/background.png"] != 1 {
+		t.Errorf("expected background image to be resolved relative to the stylesheet")
+	}
+}
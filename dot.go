@@ -0,0 +1,79 @@
+package checklinks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotEdge is a link from one page to another in a DOT graph.
+type dotEdge struct {
+	from, to string
+}
+
+// DOTFormatter writes the results as a Graphviz DOT graph, whose nodes are the
+// pages and the link targets, and whose edges are the links between them.
+// Broken links are colored red, warnings orange, and links not checked are
+// dashed. The external link targets (see Result.Internal) are drawn as
+// ellipses. A result with multiple Sources becomes an edge from every source.
+// The graph is written when the formatter is flushed.
+type DOTFormatter struct {
+	w        io.Writer
+	edges    []dotEdge
+	status   map[dotEdge]Status
+	external []string
+	seen     map[string]bool
+}
+
+// NewDOTFormatter creates a DOTFormatter writing to the given writer.
+func NewDOTFormatter(w io.Writer) *DOTFormatter {
+	return &DOTFormatter{w: w, status: make(map[dotEdge]Status), seen: make(map[string]bool)}
+}
+
+// Format adds the given result as an edge from every page linking to it. Only
+// the first result of a link between the same pages is considered.
+func (f *DOTFormatter) Format(result *Result) error {
+	if to := visitKey(result.Link.URL); !result.Internal && !f.seen[to] {
+		f.seen[to] = true
+		f.external = append(f.external, to)
+	}
+	for _, source := range result.sources() {
+		edge := dotEdge{visitKey(source), visitKey(result.Link.URL)}
+		if _, ok := f.status[edge]; ok {
+			continue
+		}
+		f.edges = append(f.edges, edge)
+		f.status[edge] = result.Status()
+	}
+	return nil
+}
+
+// Flush writes the graph with all the edges added.
+func (f *DOTFormatter) Flush() error {
+	w := bufio.NewWriter(f.w)
+	fmt.Fprintln(w, "digraph checklinks {")
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	for _, node := range f.external {
+		fmt.Fprintf(w, "\t%s [shape=ellipse];\n", dotQuote(node))
+	}
+	for _, edge := range f.edges {
+		fmt.Fprintf(w, "\t%s -> %s", dotQuote(edge.from), dotQuote(edge.to))
+		switch f.status[edge] {
+		case StatusFailed:
+			fmt.Fprint(w, " [color=red]")
+		case StatusWarning:
+			fmt.Fprint(w, " [color=orange]")
+		case StatusIgnored, StatusSkipped:
+			fmt.Fprint(w, " [style=dashed]")
+		}
+		fmt.Fprintln(w, ";")
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
+
+// dotQuote returns the given string as a quoted DOT identifier.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,59 @@
+package checklinks
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+const expectedDOT = `digraph checklinks {
+	node [shape=box];
+	"https://github.com/patrickbucher/missing" [shape=ellipse];
+	"https://no.such.host/" [shape=ellipse];
+	"mailto:info@paedubucher.ch" [shape=ellipse];
+	"https://paedubucher.ch/" -> "https://paedubucher.ch/about/";
+	"https://paedubucher.ch/" -> "https://github.com/patrickbucher/missing" [color=red];
+	"https://paedubucher.ch/about/" -> "https://no.such.host/" [color=red];
+	"https://paedubucher.ch/about/" -> "mailto:info@paedubucher.ch" [style=dashed];
+	"https://paedubucher.ch/contact/" -> "https://github.com/patrickbucher/missing" [color=red];
+}
+`
+
+func TestDOTFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewDOTFormatter(&buf)
+	about := mustParse("https://paedubucher.ch/about/")
+	results := append(append([]*Result{}, formatResults...),
+		&Result{
+			Err:  errIgnored,
+			Link: &Link{URL: mustParse("mailto:info@paedubucher.ch"), Orig: about},
+		},
+		&Result{
+			Link:     &Link{URL: mustParse("https://paedubucher.ch/about/#team"), Orig: mustParse("https://paedubucher.ch/")},
+			Internal: true,
+		},
+		&Result{
+			Err:     errors.New("GET 404 Not Found https://github.com/patrickbucher/missing"),
+			Link:    &Link{URL: mustParse("https://github.com/patrickbucher/missing"), Orig: mustParse("https://paedubucher.ch/")},
+			Sources: []*url.URL{mustParse("https://paedubucher.ch/"), mustParse("https://paedubucher.ch/contact/")},
+		},
+	)
+	for _, result := range results {
+		if err := formatter.Format(result); err != nil {
+			t.Fatalf("format result: %v", err)
+		}
+	}
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if buf.String() != expectedDOT {
+		t.Errorf("expected DOT output\n%s\ngot\n%s", expectedDOT, buf.String())
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	if actual := dotQuote(`https://example.com/"quoted"\path`); actual != `"https://example.com/\"quoted\"\\path"` {
+		t.Errorf("unexpected quoted identifier %s", actual)
+	}
+}
@@ -0,0 +1,104 @@
+package checklinks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// ErrorKind classifies the error of a failed link, e.g. to group the broken
+// links by their cause.
+type ErrorKind int
+
+const (
+	// ErrorNone indicates a result that didn't fail.
+	ErrorNone ErrorKind = iota
+
+	// ErrorDNS indicates a host name that couldn't be resolved, e.g. a dead
+	// domain.
+	ErrorDNS
+
+	// ErrorConnection indicates a connection that couldn't be established or
+	// was interrupted, e.g. because it was refused or reset.
+	ErrorConnection
+
+	// ErrorTimeout indicates a request that timed out.
+	ErrorTimeout
+
+	// ErrorTLS indicates an invalid TLS certificate or a failed handshake.
+	ErrorTLS
+
+	// ErrorStatus indicates a response with a status code that isn't
+	// considered successful, e.g. 404 Not Found.
+	ErrorStatus
+
+	// ErrorOther indicates any other error, e.g. a malformed link.
+	ErrorOther
+)
+
+// String returns a short lower-case name of the kind, e.g. "dns".
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorNone:
+		return "none"
+	case ErrorDNS:
+		return "dns"
+	case ErrorConnection:
+		return "connection"
+	case ErrorTimeout:
+		return "timeout"
+	case ErrorTLS:
+		return "tls"
+	case ErrorStatus:
+		return "status"
+	case ErrorOther:
+		return "other"
+	default:
+		return fmt.Sprintf("ErrorKind(%d)", int(k))
+	}
+}
+
+// ClassifyError returns the kind of the given error of a request, or
+// ErrorNone if the error is nil.
+func ClassifyError(err error) ErrorKind {
+	var dnsError *net.DNSError
+	var netError net.Error
+	var opError *net.OpError
+	var se *statusError
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCertificate x509.CertificateInvalidError
+	var invalidHostname x509.HostnameError
+	var recordHeader tls.RecordHeaderError
+	switch {
+	case err == nil:
+		return ErrorNone
+	case errors.As(err, &dnsError):
+		return ErrorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netError) && netError.Timeout():
+		return ErrorTimeout
+	case errors.As(err, &unknownAuthority), errors.As(err, &invalidCertificate),
+		errors.As(err, &invalidHostname), errors.As(err, &recordHeader):
+		return ErrorTLS
+	case errors.As(err, &opError), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return ErrorConnection
+	case errors.As(err, &se):
+		return ErrorStatus
+	default:
+		return ErrorOther
+	}
+}
+
+// Kind classifies the error of the result if it failed (see ClassifyError),
+// and returns ErrorNone otherwise.
+func (c Result) Kind() ErrorKind {
+	if c.Status() != StatusFailed {
+		return ErrorNone
+	}
+	return ClassifyError(c.Err)
+}
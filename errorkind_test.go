@@ -0,0 +1,77 @@
+package checklinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var classifyErrorTests = []struct {
+	err  error
+	kind ErrorKind
+}{
+	{nil, ErrorNone},
+	{&url.Error{Op: "Get", URL: "http://no.such.host/", Err: &net.OpError{Op: "dial", Net: "tcp",
+		Err: &net.DNSError{Err: "no such host", Name: "no.such.host", IsNotFound: true}}}, ErrorDNS},
+	{&url.Error{Op: "Get", URL: "http://example.com/", Err: context.DeadlineExceeded}, ErrorTimeout},
+	{&net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, ErrorTimeout},
+	{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}, ErrorConnection},
+	{&statusError{http.MethodGet, http.StatusNotFound, "http://example.com/"}, ErrorStatus},
+	{fmt.Errorf("malformed link: %w", errors.New("invalid port")), ErrorOther},
+}
+
+func TestClassifyError(t *testing.T) {
+	for _, test := range classifyErrorTests {
+		if kind := ClassifyError(test.err); kind != test.kind {
+			t.Errorf("expected %v to be classified as %v, got %v", test.err, test.kind, kind)
+		}
+	}
+}
+
+func TestResultKind(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	closed.URL = strings.Replace(closed.URL, "127.0.0.1", "localhost", 1)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	slow.URL = strings.Replace(slow.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprintf(w, `<a href="/missing">missing</a><a href="%s/refused">refused</a>
+				<a href="%s/slow">slow</a><a href="mailto:info@example.com">mail</a>`, closed.URL, slow.URL)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Timeout = 100 * time.Millisecond
+
+	kinds := make(map[string]ErrorKind)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		kinds[r.Link.URL.Path+r.Link.URL.Opaque] = r.Kind()
+	})
+	expected := map[string]ErrorKind{
+		"/":                ErrorNone,
+		"/missing":         ErrorStatus,
+		"/refused":         ErrorConnection,
+		"/slow":            ErrorTimeout,
+		"info@example.com": ErrorNone,
+	}
+	if fmt.Sprint(kinds) != fmt.Sprint(expected) {
+		t.Errorf("expected error kinds %v, got %v", expected, kinds)
+	}
+}
@@ -0,0 +1,41 @@
+package checklinks_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+
+	"github.com/patrickbucher/checklinks"
+)
+
+func ExampleCrawlPageFunc() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/about">about</a> <a href="/missing">missing</a>`)
+		case "/about":
+			fmt.Fprint(w, `<a href="/">home</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	site, _ := url.Parse(srv.URL + "/")
+	opts := checklinks.DefaultCrawlOptions()
+	opts.Client = srv.Client()
+	var lines []string
+	checklinks.CrawlPageFunc(site, opts, func(result *checklinks.Result) {
+		lines = append(lines, fmt.Sprintf("%s %s", result.Status(), result.Link.URL.Path))
+	})
+	sort.Strings(lines)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	// Output:
+	// FAIL /missing
+	// OK /
+	// OK /about
+}
@@ -0,0 +1,254 @@
+package checklinks
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Formatter writes results in a particular output format.
+type Formatter interface {
+	// Format writes the given result.
+	Format(*Result) error
+
+	// Flush writes any buffered data. It's called after the last result.
+	Flush() error
+}
+
+// Formats are the names of the output formats supported by NewFormatter.
+var Formats = []string{"text", "csv", "junit", "jsonl", "dot", "sarif", "html"}
+
+// NewFormatter returns a Formatter for the output format with the given name
+// (see Formats) writing to the given writer. An error is returned if there is
+// no such format.
+func NewFormatter(format string, w io.Writer) (Formatter, error) {
+	switch format {
+	case "text":
+		return NewTextFormatter(w), nil
+	case "csv":
+		return NewCSVFormatter(w), nil
+	case "junit":
+		return NewJUnitFormatter(w), nil
+	case "jsonl":
+		return NewJSONLFormatter(w), nil
+	case "dot":
+		return NewDOTFormatter(w), nil
+	case "sarif":
+		return NewSARIFFormatter(w), nil
+	case "html":
+		return NewHTMLFormatter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// ResultWriter writes results using a Formatter, and can be used by multiple
+// goroutines concurrently: every result is written entirely before the next
+// one, so that the output of the results is never interleaved. It implements
+// Formatter itself, e.g. to be used as the CrawlOptions' Formatter.
+type ResultWriter struct {
+	mu        sync.Mutex
+	formatter Formatter
+}
+
+// NewResultWriter creates a ResultWriter writing results using the given
+// Formatter.
+func NewResultWriter(f Formatter) *ResultWriter {
+	return &ResultWriter{formatter: f}
+}
+
+// Format writes the given result using the Formatter.
+func (w *ResultWriter) Format(result *Result) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.formatter.Format(result)
+}
+
+// Flush flushes the Formatter.
+func (w *ResultWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.formatter.Flush()
+}
+
+// statusOrder is the order of the groups of results written by a
+// SortingFormatter: the problems first, the links that work last.
+var statusOrder = map[Status]int{
+	StatusFailed:  0,
+	StatusWarning: 1,
+	StatusSkipped: 2,
+	StatusIgnored: 3,
+	StatusOK:      4,
+}
+
+// SortingFormatter collects the results, and writes them using another
+// Formatter when flushed: grouped by their status (failed links first,
+// followed by warnings, skipped, ignored, and the links that work), and
+// sorted by their URLs, and the URLs of the pages they were found on. Unlike
+// the order the results are available in, which depends on the timing of the
+// requests, this order is the same for every crawl of an unchanged site, so
+// that the outputs of different crawls can be compared.
+type SortingFormatter struct {
+	formatter Formatter
+	results   []*Result
+}
+
+// NewSortingFormatter creates a SortingFormatter writing the sorted results
+// using the given Formatter.
+func NewSortingFormatter(f Formatter) *SortingFormatter {
+	return &SortingFormatter{formatter: f, results: make([]*Result, 0)}
+}
+
+// Format collects the given result.
+func (f *SortingFormatter) Format(result *Result) error {
+	f.results = append(f.results, result)
+	return nil
+}
+
+// Flush sorts the collected results, writes them, and flushes the Formatter.
+func (f *SortingFormatter) Flush() error {
+	sort.SliceStable(f.results, func(i, j int) bool {
+		a, b := f.results[i], f.results[j]
+		if sa, sb := statusOrder[a.Status()], statusOrder[b.Status()]; sa != sb {
+			return sa < sb
+		}
+		if ua, ub := a.Link.URL.String(), b.Link.URL.String(); ua != ub {
+			return ua < ub
+		}
+		return a.Link.Orig.String() < b.Link.Orig.String()
+	})
+	for _, result := range f.results {
+		if err := f.formatter.Format(result); err != nil {
+			return err
+		}
+	}
+	f.results = f.results[:0]
+	return f.formatter.Flush()
+}
+
+// TextFormatter writes every result as a line as returned by Result.String,
+// followed by an indented line for every further page in the result's
+// Sources.
+type TextFormatter struct {
+	w io.Writer
+
+	// Trace enables writing an indented line with the link's trail after
+	// every result, e.g. found at: "A" > "B" > "link".
+	Trace bool
+
+	// Tag enables appending whether the link is internal or external (see
+	// Result.Internal) to every result's line, e.g. [external].
+	Tag bool
+
+	// LinkText enables writing an indented line with the link's text (see
+	// Link.Text) after every result that has one, e.g. text: "Download".
+	LinkText bool
+}
+
+// NewTextFormatter creates a TextFormatter writing to the given writer.
+func NewTextFormatter(w io.Writer) *TextFormatter {
+	return &TextFormatter{w: w}
+}
+
+// Format writes the given result as a line (tagged, if enabled), followed by
+// its text and trail (if enabled) and further sources.
+func (f *TextFormatter) Format(result *Result) error {
+	line := result.String()
+	if f.Tag && result.Internal {
+		line += " [internal]"
+	} else if f.Tag {
+		line += " [external]"
+	}
+	if _, err := fmt.Fprintln(f.w, line); err != nil {
+		return err
+	}
+	if f.LinkText && result.Link.Text != "" {
+		if _, err := fmt.Fprintf(f.w, "\ttext: %q\n", result.Link.Text); err != nil {
+			return err
+		}
+	}
+	if f.Trace {
+		trail := make([]string, 0)
+		for _, u := range append(result.Link.Trail(), result.Link.URL) {
+			trail = append(trail, fmt.Sprintf(`"%s"`, u))
+		}
+		if _, err := fmt.Fprintf(f.w, "\tfound at: %s\n", strings.Join(trail, " > ")); err != nil {
+			return err
+		}
+	}
+	for _, source := range result.Sources {
+		if source.String() == result.Link.Orig.String() {
+			continue
+		}
+		if _, err := fmt.Fprintf(f.w, "\talso from \"%s\"\n", source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush does nothing, because the lines are written unbuffered.
+func (f *TextFormatter) Flush() error {
+	return nil
+}
+
+// CSVHeader contains the names of the columns written by CSVFormatter.
+var CSVHeader = []string{"from_url", "to_url", "status", "status_code", "error", "internal", "content_type"}
+
+// CSVFormatter writes a header row and one row per result in the CSV format.
+// A result with multiple Sources is written as one row per source.
+type CSVFormatter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// NewCSVFormatter creates a CSVFormatter writing to the given writer.
+func NewCSVFormatter(w io.Writer) *CSVFormatter {
+	return &CSVFormatter{w: csv.NewWriter(w)}
+}
+
+// Format writes the given result as a row per source, preceded by the header
+// row for the first result.
+func (f *CSVFormatter) Format(result *Result) error {
+	if !f.headerWritten {
+		if err := f.w.Write(CSVHeader); err != nil {
+			return err
+		}
+		f.headerWritten = true
+	}
+	var errText string
+	if result.Err != nil {
+		errText = result.Err.Error()
+	}
+	for _, source := range result.sources() {
+		if err := f.w.Write([]string{
+			source.String(),
+			result.Link.URL.String(),
+			result.Status().String(),
+			strconv.Itoa(result.StatusCode),
+			errText,
+			strconv.FormatBool(result.Internal),
+			result.ContentType,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes the header row (if no results have been written) and all the
+// buffered rows.
+func (f *CSVFormatter) Flush() error {
+	if !f.headerWritten {
+		if err := f.w.Write(CSVHeader); err != nil {
+			return err
+		}
+		f.headerWritten = true
+	}
+	f.w.Flush()
+	return f.w.Error()
+}
@@ -0,0 +1,194 @@
+package checklinks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func mustParse(address string) *url.URL {
+	u, err := url.Parse(address)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+var formatResults = []*Result{
+	{
+		Link:        &Link{URL: mustParse("https://paedubucher.ch/about/"), Orig: mustParse("https://paedubucher.ch/")},
+		StatusCode:  200,
+		ContentType: "text/html; charset=utf-8",
+		Internal:    true,
+	},
+	{
+		Err:        &statusError{"GET", 404, "https://github.com/patrickbucher/missing"},
+		Link:       &Link{URL: mustParse("https://github.com/patrickbucher/missing"), Orig: mustParse("https://paedubucher.ch/")},
+		StatusCode: 404,
+	},
+	{
+		Err:  errors.New("dial tcp: lookup no.such.host, port 443: no such host"),
+		Link: &Link{URL: mustParse("https://no.such.host/"), Orig: mustParse("https://paedubucher.ch/about/")},
+	},
+}
+
+const expectedCSV = `from_url,to_url,status,status_code,error,internal,content_type
+https://paedubucher.ch/,https://paedubucher.ch/about/,OK,200,,true,text/html; charset=utf-8
+https://paedubucher.ch/,https://github.com/patrickbucher/missing,FAIL,404,GET 404 Not Found https://github.com/patrickbucher/missing,false,
+https://paedubucher.ch/about/,https://no.such.host/,FAIL,0,"dial tcp: lookup no.such.host, port 443: no such host",false,
+`
+
+func TestCSVFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewCSVFormatter(&buf)
+	for _, result := range formatResults {
+		if err := formatter.Format(result); err != nil {
+			t.Fatalf("format result: %v", err)
+		}
+	}
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if buf.String() != expectedCSV {
+		t.Errorf("expected CSV output\n%s\ngot\n%s", expectedCSV, buf.String())
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	for _, format := range Formats {
+		if _, err := NewFormatter(format, &bytes.Buffer{}); err != nil {
+			t.Errorf("create formatter for %s: %v", format, err)
+		}
+	}
+	if _, err := NewFormatter("docx", &bytes.Buffer{}); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+func TestFormatSources(t *testing.T) {
+	result := &Result{
+		Err:        errors.New("GET 404 Not Found https://paedubucher.ch/old"),
+		Link:       &Link{URL: mustParse("https://paedubucher.ch/old"), Orig: mustParse("https://paedubucher.ch/")},
+		StatusCode: 404,
+		Sources:    []*url.URL{mustParse("https://paedubucher.ch/"), mustParse("https://paedubucher.ch/about/")},
+		Internal:   true,
+	}
+
+	var text bytes.Buffer
+	NewTextFormatter(&text).Format(result)
+	expectedText := `FAIL "https://paedubucher.ch/old": from "https://paedubucher.ch/" GET 404 Not Found https://paedubucher.ch/old
+	also from "https://paedubucher.ch/about/"
+`
+	if text.String() != expectedText {
+		t.Errorf("expected text output\n%s\ngot\n%s", expectedText, text.String())
+	}
+
+	var csv bytes.Buffer
+	formatter := NewCSVFormatter(&csv)
+	formatter.Format(result)
+	formatter.Flush()
+	expectedCSV := `from_url,to_url,status,status_code,error,internal,content_type
+https://paedubucher.ch/,https://paedubucher.ch/old,FAIL,404,GET 404 Not Found https://paedubucher.ch/old,true,
+https://paedubucher.ch/about/,https://paedubucher.ch/old,FAIL,404,GET 404 Not Found https://paedubucher.ch/old,true,
+`
+	if csv.String() != expectedCSV {
+		t.Errorf("expected CSV output\n%s\ngot\n%s", expectedCSV, csv.String())
+	}
+}
+
+// yieldingWriter yields the processor after every write, which provokes
+// interleaved output if it's written to concurrently.
+type yieldingWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *yieldingWriter) Write(p []byte) (int, error) {
+	n, err := w.buf.Write(p)
+	runtime.Gosched()
+	return n, err
+}
+
+func TestResultWriter(t *testing.T) {
+	const n = 50
+	var out yieldingWriter
+	formatter := NewTextFormatter(&out)
+	formatter.Trace = true
+	writer := NewResultWriter(formatter)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			page := mustParse("https://paedubucher.ch/")
+			link := &Link{URL: mustParse(fmt.Sprintf("https://paedubucher.ch/%d", i)), Orig: page}
+			writer.Format(&Result{Err: errors.New("broken"), Link: link})
+		}(i)
+	}
+	wg.Wait()
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.buf.String(), "\n"), "\n")
+	if len(lines) != 2*n {
+		t.Fatalf("expected %d lines, got %d", 2*n, len(lines))
+	}
+	for i := 0; i < len(lines); i += 2 {
+		var id int
+		if _, err := fmt.Sscanf(lines[i], `FAIL "https://paedubucher.ch/%d"`, &id); err != nil {
+			t.Fatalf("expected result line, got %q", lines[i])
+		}
+		trail := fmt.Sprintf("\tfound at: \"https://paedubucher.ch/\" > \"https://paedubucher.ch/%d\"", id)
+		if lines[i+1] != trail {
+			t.Errorf("expected trail %q after %q, got %q", trail, lines[i], lines[i+1])
+		}
+	}
+}
+
+func TestSortingFormatter(t *testing.T) {
+	var out strings.Builder
+	formatter := NewSortingFormatter(NewTextFormatter(&out))
+	home := mustParse("https://example.com/")
+	about := mustParse("https://example.com/about")
+	results := []*Result{
+		{Link: &Link{URL: mustParse("https://example.com/b"), Orig: home}},
+		{Err: fmt.Errorf("%w: mailto", errIgnored), Link: &Link{URL: mustParse("mailto:info@example.com"), Orig: home}},
+		{Err: errors.New("broken"), Link: &Link{URL: mustParse("https://example.com/gone"), Orig: home}},
+		{Link: &Link{URL: mustParse("https://example.com/a"), Orig: home}},
+		{Err: fmt.Errorf("%w: slow", errWarning), Link: &Link{URL: mustParse("https://example.com/slow"), Orig: home}},
+		{Err: errors.New("broken"), Link: &Link{URL: mustParse("https://example.com/dead"), Orig: about}},
+		{Err: errors.New("broken"), Link: &Link{URL: mustParse("https://example.com/dead"), Orig: home}},
+	}
+	for _, result := range results {
+		formatter.Format(result)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output before flush, got %q", out.String())
+	}
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	var actual []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		fields := strings.Fields(line)
+		actual = append(actual, fields[0]+" "+strings.TrimSuffix(fields[1], ":")+" "+fields[3])
+	}
+	expected := []string{
+		`FAIL "https://example.com/dead" "https://example.com/"`,
+		`FAIL "https://example.com/dead" "https://example.com/about"`,
+		`FAIL "https://example.com/gone" "https://example.com/"`,
+		`WARN "https://example.com/slow" "https://example.com/"`,
+		`IGNORE "mailto:info@example.com" "https://example.com/"`,
+		`OK "https://example.com/a" "https://example.com/"`,
+		`OK "https://example.com/b" "https://example.com/"`,
+	}
+	if !isEqual(actual, expected) {
+		t.Errorf("expected sorted results\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
@@ -1,5 +1,7 @@
 module github.com/patrickbucher/checklinks
 
-go 1.18
+go 1.21
 
 require golang.org/x/net v0.0.0-20220412020605-290c469a71a5
+
+require golang.org/x/text v0.3.7 // indirect
@@ -0,0 +1,89 @@
+package checklinks
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// hostLimiter limits the number of concurrent requests per host. The
+// semaphores of the hosts are only kept while requests to them are waiting or
+// open, so that the limiter doesn't grow with the number of hosts crawled.
+type hostLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	hosts map[string]*hostSemaphore
+}
+
+// hostSemaphore limits the concurrent requests to a host, and counts the
+// requests waiting for or holding it.
+type hostSemaphore struct {
+	slots chan struct{}
+	users int
+}
+
+// newHostLimiter creates a limiter allowing the given number of concurrent
+// requests per host, or nil (which doesn't limit anything) if the limit is
+// below 1.
+func newHostLimiter(limit int) *hostLimiter {
+	if limit < 1 {
+		return nil
+	}
+	return &hostLimiter{limit: limit, hosts: make(map[string]*hostSemaphore)}
+}
+
+// acquire waits for a slot of the given host, and returns the context's error
+// if the given context is done before.
+func (h *hostLimiter) acquire(ctx context.Context, host string) error {
+	if h == nil {
+		return nil
+	}
+	host = strings.ToLower(host)
+	h.mu.Lock()
+	sem, ok := h.hosts[host]
+	if !ok {
+		sem = &hostSemaphore{slots: make(chan struct{}, h.limit)}
+		h.hosts[host] = sem
+	}
+	sem.users++
+	h.mu.Unlock()
+	select {
+	case sem.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		h.leave(host, sem)
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of the given host acquired before.
+func (h *hostLimiter) release(host string) {
+	if h == nil {
+		return
+	}
+	host = strings.ToLower(host)
+	h.mu.Lock()
+	sem := h.hosts[host]
+	h.mu.Unlock()
+	<-sem.slots
+	h.leave(host, sem)
+}
+
+// leave removes the given semaphore of the given host if it isn't used by
+// any other request.
+func (h *hostLimiter) leave(host string, sem *hostSemaphore) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sem.users--
+	if sem.users == 0 {
+		delete(h.hosts, host)
+	}
+}
+
+// size returns the number of hosts with requests waiting or open.
+func (h *hostLimiter) size() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.hosts)
+}
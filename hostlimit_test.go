@@ -0,0 +1,107 @@
+package checklinks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(2)
+	var mu sync.Mutex
+	open := make(map[string]int)
+	maxOpen := make(map[string]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		host := fmt.Sprintf("host%d.example.com", i%3)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.acquire(context.Background(), host); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			open[host]++
+			if open[host] > maxOpen[host] {
+				maxOpen[host] = open[host]
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			open[host]--
+			mu.Unlock()
+			limiter.release(host)
+		}()
+	}
+	wg.Wait()
+	for host, n := range maxOpen {
+		if n > 2 {
+			t.Errorf("expected at most 2 concurrent requests to %s, got %d", host, n)
+		}
+	}
+	if size := limiter.size(); size != 0 {
+		t.Errorf("expected the semaphores to be removed, got %d", size)
+	}
+
+	if err := limiter.acquire(context.Background(), "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.acquire(context.Background(), "EXAMPLE.com"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.acquire(ctx, "example.com"); err == nil {
+		t.Error("expected acquiring a third slot to time out")
+	}
+	limiter.release("example.com")
+	limiter.release("example.com")
+	if size := limiter.size(); size != 0 {
+		t.Errorf("expected the semaphore to be removed, got %d", size)
+	}
+
+	var unlimited *hostLimiter
+	if err := unlimited.acquire(ctx, "example.com"); err != nil {
+		t.Errorf("expected a nil limiter not to limit, got %v", err)
+	}
+	unlimited.release("example.com")
+}
+
+func TestPerHostParallelism(t *testing.T) {
+	var mu sync.Mutex
+	var open, maxOpen int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			for i := 0; i < 20; i++ {
+				fmt.Fprintf(w, `<a href="/%d">%d</a>`, i, i)
+			}
+			return
+		}
+		mu.Lock()
+		open++
+		if open > maxOpen {
+			maxOpen = open
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		open--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.Parallelism = 16
+	opts.PerHostParallelism = 3
+	if failed := CrawlPageWithOptions(mustParse(srv.URL+"/"), opts); failed != 0 {
+		t.Errorf("expected no failed links, got %d", failed)
+	}
+	if maxOpen > 3 {
+		t.Errorf("expected at most 3 concurrent requests, got %d", maxOpen)
+	}
+}
@@ -0,0 +1,148 @@
+package checklinks
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+// htmlReportTemplate is the template of the report written by an
+// HTMLFormatter, which is self-contained: the styles are embedded, and there
+// are no scripts.
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Link Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { font-size: 1.1em; margin-top: 2em; word-break: break-all; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+td.url { word-break: break-all; }
+.status { font-weight: bold; white-space: nowrap; }
+.fail { background: #fdecea; } .fail .status { color: #b71c1c; }
+.warn { background: #fff8e1; } .warn .status { color: #e65100; }
+.skip .status, .ignore .status { color: #757575; }
+.ok .status { color: #2e7d32; }
+</style>
+</head>
+<body>
+<h1>Link Report</h1>
+<p>{{range $i, $count := .Counts}}{{if $i}}, {{end}}<span class="{{$count.Class}}"><span class="status">{{$count.Status}}</span>: {{$count.Links}}</span>{{else}}No links reported.{{end}}</p>
+{{range .Pages}}<h2>Found on <a href="{{.URL}}">{{.URL}}</a></h2>
+<table>
+<tr><th>Status</th><th>Link</th><th>Type</th><th>Code</th><th>Details</th></tr>
+{{range .Links}}<tr class="{{.Class}}"><td class="status">{{.Status}}</td><td class="url"><a href="{{.URL}}">{{.URL}}</a></td><td>{{if .Internal}}internal{{else}}external{{end}}</td><td>{{if .StatusCode}}{{.StatusCode}}{{end}}</td><td>{{.Details}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// htmlReport is the data of the report written by an HTMLFormatter.
+type htmlReport struct {
+	Counts []htmlCount
+	Pages  []*htmlPage
+}
+
+// htmlCount is the number of links reported with a status.
+type htmlCount struct {
+	Status string
+	Class  string
+	Links  int
+}
+
+// htmlPage holds the links reported for a page they were found on.
+type htmlPage struct {
+	URL   string
+	Links []htmlLink
+}
+
+// htmlLink is a link reported in an HTMLFormatter's report.
+type htmlLink struct {
+	Status     string
+	Class      string
+	URL        string
+	Internal   bool
+	StatusCode int
+	Details    string
+	order      int
+}
+
+// HTMLFormatter writes the results as a self-contained HTML page, e.g. to be
+// shared with people not reading the text output. The links are grouped by
+// the page they were found on, and every link and page can be opened from the
+// report, which tells whether the links are internal (see Result.Internal).
+// Within a page, the links are sorted like by a SortingFormatter: failed links
+// first, highlighted in red, followed by warnings, highlighted in yellow, and
+// the others. A result with multiple Sources is listed for every source. The
+// report is written when the formatter is flushed.
+type HTMLFormatter struct {
+	w      io.Writer
+	pages  map[string]*htmlPage
+	counts map[Status]int
+}
+
+// NewHTMLFormatter creates an HTMLFormatter writing to the given writer.
+func NewHTMLFormatter(w io.Writer) *HTMLFormatter {
+	return &HTMLFormatter{
+		w:      w,
+		pages:  make(map[string]*htmlPage),
+		counts: make(map[Status]int),
+	}
+}
+
+// Format adds the given result to the links of every page it was found on.
+func (f *HTMLFormatter) Format(result *Result) error {
+	status := result.Status()
+	f.counts[status]++
+	link := htmlLink{
+		Status:     status.String(),
+		Class:      strings.ToLower(status.String()),
+		URL:        result.Link.URL.String(),
+		Internal:   result.Internal,
+		StatusCode: result.StatusCode,
+		order:      statusOrder[status],
+	}
+	if result.Err != nil {
+		link.Details = result.Err.Error()
+	} else if result.Redirect != nil {
+		link.Details = result.Redirect.String()
+	}
+	for _, source := range result.sources() {
+		from := source.String()
+		page, ok := f.pages[from]
+		if !ok {
+			page = &htmlPage{URL: from}
+			f.pages[from] = page
+		}
+		page.Links = append(page.Links, link)
+	}
+	return nil
+}
+
+// Flush writes the HTML report, whose pages are sorted by their URLs.
+func (f *HTMLFormatter) Flush() error {
+	var report htmlReport
+	statuses := []Status{StatusFailed, StatusWarning, StatusSkipped, StatusIgnored, StatusOK}
+	for _, status := range statuses {
+		if n := f.counts[status]; n > 0 {
+			report.Counts = append(report.Counts, htmlCount{status.String(), strings.ToLower(status.String()), n})
+		}
+	}
+	for _, page := range f.pages {
+		sort.SliceStable(page.Links, func(i, j int) bool {
+			a, b := page.Links[i], page.Links[j]
+			if a.order != b.order {
+				return a.order < b.order
+			}
+			return a.URL < b.URL
+		})
+		report.Pages = append(report.Pages, page)
+	}
+	sort.Slice(report.Pages, func(i, j int) bool {
+		return report.Pages[i].URL < report.Pages[j].URL
+	})
+	return htmlReportTemplate.Execute(f.w, report)
+}
@@ -0,0 +1,71 @@
+package checklinks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTMLFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewHTMLFormatter(&buf)
+	results := append(formatResults, &Result{
+		Err:      fmt.Errorf("%w: <script> in message", errWarning),
+		Link:     &Link{URL: mustParse("https://paedubucher.ch/a?b=c&d=e"), Orig: mustParse("https://paedubucher.ch/")},
+		Internal: true,
+	})
+	for _, result := range results {
+		if err := formatter.Format(result); err != nil {
+			t.Fatalf("format result: %v", err)
+		}
+	}
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	report := buf.String()
+	for _, expected := range []string{
+		`<span class="fail"><span class="status">FAIL</span>: 2</span>, <span class="warn"><span class="status">WARN</span>: 1</span>, <span class="ok"><span class="status">OK</span>: 1</span>`,
+		`<h2>Found on <a href="https://paedubucher.ch/">https://paedubucher.ch/</a></h2>`,
+		`<h2>Found on <a href="https://paedubucher.ch/about/">https://paedubucher.ch/about/</a></h2>`,
+		`<tr class="fail"><td class="status">FAIL</td><td class="url"><a href="https://github.com/patrickbucher/missing">https://github.com/patrickbucher/missing</a></td><td>external</td><td>404</td><td>GET 404 Not Found https://github.com/patrickbucher/missing</td></tr>`,
+		`<tr class="warn"><td class="status">WARN</td><td class="url"><a href="https://paedubucher.ch/a?b=c&amp;d=e">https://paedubucher.ch/a?b=c&amp;d=e</a></td><td>internal</td><td></td><td>warning: &lt;script&gt; in message</td></tr>`,
+		`<tr class="fail"><td class="status">FAIL</td><td class="url"><a href="https://no.such.host/">https://no.such.host/</a></td><td>external</td><td></td><td>dial tcp: lookup no.such.host, port 443: no such host</td></tr>`,
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected report to contain\n%s\ngot\n%s", expected, report)
+		}
+	}
+	// The pages are sorted, and so are the links of a page: failed first,
+	// followed by warnings, and OK last.
+	order := []string{"https://github.com/patrickbucher/missing", "https://paedubucher.ch/a?", "https://paedubucher.ch/about/", "https://no.such.host/"}
+	var last int
+	for _, s := range order {
+		i := strings.Index(report, `class="url"><a href="`+s)
+		if i < last {
+			t.Errorf("expected %s after the previous links, got it at %d before %d", s, i, last)
+		}
+		last = i
+	}
+}
+
+func TestHTMLFormatterSources(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewHTMLFormatter(&buf)
+	formatter.Format(&Result{
+		Err:     errors.New("broken"),
+		Link:    &Link{URL: mustParse("https://example.com/broken"), Orig: mustParse("https://example.com/a")},
+		Sources: []*url.URL{mustParse("https://example.com/a"), mustParse("https://example.com/b")},
+	})
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if n := strings.Count(buf.String(), `<a href="https://example.com/broken">`); n != 2 {
+		t.Errorf("expected broken link listed for both sources, got %d times", n)
+	}
+	if n := strings.Count(buf.String(), "<h2>"); n != 2 {
+		t.Errorf("expected 2 pages, got %d", n)
+	}
+}
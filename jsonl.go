@@ -0,0 +1,94 @@
+package checklinks
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+)
+
+// jsonlRecord is a result as written by JSONLFormatter.
+type jsonlRecord struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Line        int    `json:"line,omitempty"`
+	Column      int    `json:"column,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Status      string `json:"status"`
+	StatusCode  int    `json:"status_code"`
+	Error       string `json:"error,omitempty"`
+	ErrorKind   string `json:"error_kind,omitempty"`
+	Redirect    string `json:"redirect,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Internal    bool   `json:"internal"`
+}
+
+// JSONLFormatter writes every result as a JSON object on a line of its own
+// (JSON Lines) as soon as it's available, so that the results are not lost if
+// the crawl is interrupted. See ReadDone for resuming a crawl.
+type JSONLFormatter struct {
+	encoder *json.Encoder
+}
+
+// NewJSONLFormatter creates a JSONLFormatter writing to the given writer.
+func NewJSONLFormatter(w io.Writer) *JSONLFormatter {
+	return &JSONLFormatter{encoder: json.NewEncoder(w)}
+}
+
+// Format writes the given result as a line with a JSON object.
+func (f *JSONLFormatter) Format(result *Result) error {
+	record := jsonlRecord{
+		From:        result.Link.Orig.String(),
+		To:          result.Link.URL.String(),
+		Line:        result.Link.Line,
+		Column:      result.Link.Column,
+		Text:        result.Link.Text,
+		Status:      result.Status().String(),
+		StatusCode:  result.StatusCode,
+		ContentType: result.ContentType,
+		Internal:    result.Internal,
+	}
+	if result.Err != nil {
+		record.Error = result.Err.Error()
+	}
+	if kind := result.Kind(); kind != ErrorNone {
+		record.ErrorKind = kind.String()
+	}
+	if result.Redirect != nil {
+		record.Redirect = result.Redirect.URL.String()
+	}
+	return f.encoder.Encode(record)
+}
+
+// Flush does nothing, because the lines are written unbuffered.
+func (f *JSONLFormatter) Flush() error {
+	return nil
+}
+
+// ReadDone reads the results written by a JSONLFormatter, and returns the
+// URLs of the links that don't need to be checked again when resuming the
+// crawl (see CrawlOptions.Done): the ones reported as OK or ignored. Failed and
+// skipped links are checked again. Malformed lines, e.g. a truncated line
+// written by an interrupted crawl, are skipped.
+func ReadDone(r io.Reader) (map[string]bool, error) {
+	done := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record jsonlRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		u, err := url.Parse(record.To)
+		if err != nil {
+			continue
+		}
+		if record.Status == StatusOK.String() || record.Status == StatusIgnored.String() {
+			done[visitKey(u)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return done, nil
+}
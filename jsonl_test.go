@@ -0,0 +1,91 @@
+package checklinks
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const expectedJSONL = `{"from":"https://paedubucher.ch/","to":"https://paedubucher.ch/about/","status":"OK","status_code":200,"content_type":"text/html; charset=utf-8","internal":true}
+{"from":"https://paedubucher.ch/","to":"https://github.com/patrickbucher/missing","status":"FAIL","status_code":404,"error":"GET 404 Not Found https://github.com/patrickbucher/missing","error_kind":"status","internal":false}
+{"from":"https://paedubucher.ch/about/","to":"https://no.such.host/","status":"FAIL","status_code":0,"error":"dial tcp: lookup no.such.host, port 443: no such host","error_kind":"other","internal":false}
+`
+
+func TestJSONLFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewJSONLFormatter(&buf)
+	for _, result := range formatResults {
+		if err := formatter.Format(result); err != nil {
+			t.Fatalf("format result: %v", err)
+		}
+	}
+	if buf.String() != expectedJSONL {
+		t.Errorf("expected JSONL output\n%s\ngot\n%s", expectedJSONL, buf.String())
+	}
+}
+
+func TestReadDone(t *testing.T) {
+	input := expectedJSONL +
+		`{"from":"https://paedubucher.ch/","to":"mailto:info@paedubucher.ch","status":"IGNORE","status_code":0}` + "\n" +
+		`{"from":"https://paedubucher.ch/","to":"https://paedubucher.ch/#top","status":"OK","status_code":200}` + "\n" +
+		`{"from":"https://paedubucher.ch/","to":"https://paedubucher.ch/trunc`
+	done, err := ReadDone(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("read done: %v", err)
+	}
+	expected := map[string]bool{
+		"https://paedubucher.ch/about/": true,
+		"mailto:info@paedubucher.ch":    true,
+		"https://paedubucher.ch/":       true,
+	}
+	if !reflect.DeepEqual(done, expected) {
+		t.Errorf("expected done links %v, got %v", expected, done)
+	}
+}
+
+func TestResume(t *testing.T) {
+	var mu sync.Mutex
+	var external string
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<a href="/page.html">page</a><a href="%s/file.pdf">file</a><a href="/missing">missing</a>`, external)
+		case "/page.html":
+			fmt.Fprintf(w, `<a href="%s/other.pdf">other</a>`, external)
+		case "/file.pdf", "/other.pdf":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	// Use another host name for the leaves, so that they are external.
+	external = strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	opts := DefaultCrawlOptions()
+	opts.Done = map[string]bool{
+		srv.URL + "/":           true,
+		srv.URL + "/page.html":  true,
+		external + "/file.pdf":  true,
+		external + "/other.pdf": true,
+	}
+
+	var reported []string
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		reported = append(reported, r.Link.URL.Path)
+	})
+	if !isEqual(reported, []string{"/missing"}) {
+		t.Errorf("expected only the links not done to be reported, got %v", reported)
+	}
+	expected := map[string]int{"GET /": 1, "GET /page.html": 1, "GET /missing": 1}
+	if !reflect.DeepEqual(hits, expected) {
+		t.Errorf("expected requests %v, got %v", expected, hits)
+	}
+}
@@ -0,0 +1,120 @@
+package checklinks
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	ClassName  string          `xml:"classname,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnitFormatter writes the results as a JUnit XML report, which is understood
+// by many CI systems. Every result becomes a test case, and the test cases are
+// grouped into test suites by the page the link was found on. Failed links are
+// reported as failures, ignored and skipped links as skipped test cases, and
+// warnings as passed test cases with the warning as their output. Every test
+// case has an "internal" property telling whether the link is internal (see
+// Result.Internal). A result with multiple Sources becomes a test case in
+// every source's suite. The report is written when the formatter is flushed.
+type JUnitFormatter struct {
+	w      io.Writer
+	report junitTestSuites
+	suites map[string]*junitTestSuite
+}
+
+// NewJUnitFormatter creates a JUnitFormatter writing to the given writer.
+func NewJUnitFormatter(w io.Writer) *JUnitFormatter {
+	return &JUnitFormatter{
+		w:      w,
+		report: junitTestSuites{Name: "checklinks"},
+		suites: make(map[string]*junitTestSuite),
+	}
+}
+
+// Format adds the given result as a test case to the test suite of every page
+// the link was found on.
+func (f *JUnitFormatter) Format(result *Result) error {
+	for _, source := range result.sources() {
+		f.addCase(source.String(), result)
+	}
+	return nil
+}
+
+// addCase adds the given result as a test case to the test suite of the given
+// page.
+func (f *JUnitFormatter) addCase(from string, result *Result) {
+	suite, ok := f.suites[from]
+	if !ok {
+		suite = &junitTestSuite{Name: from}
+		f.suites[from] = suite
+		f.report.Suites = append(f.report.Suites, suite)
+	}
+	testCase := &junitTestCase{
+		Name:       result.Link.URL.String(),
+		ClassName:  from,
+		Properties: []junitProperty{{"internal", strconv.FormatBool(result.Internal)}},
+	}
+	switch result.Status() {
+	case StatusFailed:
+		testCase.Failure = &junitMessage{Message: result.Err.Error(), Text: result.String()}
+		suite.Failures++
+		f.report.Failures++
+	case StatusIgnored, StatusSkipped:
+		testCase.Skipped = &junitMessage{Message: result.Err.Error()}
+		suite.Skipped++
+		f.report.Skipped++
+	case StatusWarning:
+		testCase.SystemOut = result.String()
+	}
+	suite.Tests++
+	f.report.Tests++
+	suite.Cases = append(suite.Cases, testCase)
+}
+
+// Flush writes the XML report.
+func (f *JUnitFormatter) Flush() error {
+	if _, err := io.WriteString(f.w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(f.w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(f.report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(f.w, "\n")
+	return err
+}
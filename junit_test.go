@@ -0,0 +1,48 @@
+package checklinks
+
+import (
+	"bytes"
+	"testing"
+)
+
+const expectedJUnit = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="checklinks" tests="3" failures="2" skipped="0">
+  <testsuite name="https://paedubucher.ch/" tests="2" failures="1" skipped="0">
+    <testcase name="https://paedubucher.ch/about/" classname="https://paedubucher.ch/">
+      <properties>
+        <property name="internal" value="true"></property>
+      </properties>
+    </testcase>
+    <testcase name="https://github.com/patrickbucher/missing" classname="https://paedubucher.ch/">
+      <properties>
+        <property name="internal" value="false"></property>
+      </properties>
+      <failure message="GET 404 Not Found https://github.com/patrickbucher/missing">FAIL &#34;https://github.com/patrickbucher/missing&#34;: from &#34;https://paedubucher.ch/&#34; GET 404 Not Found https://github.com/patrickbucher/missing</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="https://paedubucher.ch/about/" tests="1" failures="1" skipped="0">
+    <testcase name="https://no.such.host/" classname="https://paedubucher.ch/about/">
+      <properties>
+        <property name="internal" value="false"></property>
+      </properties>
+      <failure message="dial tcp: lookup no.such.host, port 443: no such host">FAIL &#34;https://no.such.host/&#34;: from &#34;https://paedubucher.ch/about/&#34; dial tcp: lookup no.such.host, port 443: no such host</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+func TestJUnitFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewJUnitFormatter(&buf)
+	for _, result := range formatResults {
+		if err := formatter.Format(result); err != nil {
+			t.Fatalf("format result: %v", err)
+		}
+	}
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if buf.String() != expectedJUnit {
+		t.Errorf("expected JUnit output\n%s\ngot\n%s", expectedJUnit, buf.String())
+	}
+}
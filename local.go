@@ -0,0 +1,202 @@
+package checklinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// CrawlLocal crawls the HTML file at the given path, or the index.html file of
+// the given directory, without a web server, e.g. to check a static site
+// before deploying it. The directory (or the file's directory) is the root
+// of the site: links are resolved against it, and reported as failed if the
+// file they point to doesn't exist. External links are checked over HTTP as
+// usual. The results are reported according to the given options. The number
+// of failed links is returned, or an error if the path cannot be accessed.
+func CrawlLocal(filePath string, opts CrawlOptions) (int, error) {
+	summary, err := CrawlLocalContext(context.Background(), filePath, opts)
+	if err != nil {
+		return 0, err
+	}
+	return summary.Count(StatusFailed), nil
+}
+
+// CrawlLocalContext is like CrawlLocal, but stops the crawl when the given
+// context is done, like CrawlPageContext. A summary of all the results is
+// returned.
+func CrawlLocalContext(ctx context.Context, filePath string, opts CrawlOptions) (*CrawlSummary, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	root, page := filePath, "/"
+	if !info.IsDir() {
+		root, page = filepath.Dir(filePath), "/"+filepath.Base(filePath)
+	}
+	opts.Files = os.DirFS(root)
+	site := &url.URL{Scheme: "file", Path: page}
+	return crawlAndReport(ctx, newClient(&opts), []*Link{{URL: site, Orig: site}}, opts), nil
+}
+
+// localPageExtensions are the file extensions of the local files crawled by
+// CrawlLocalFiles.
+var localPageExtensions = []string{"html", "htm", "xhtml"}
+
+// CrawlLocalFiles is like CrawlLocal, but only checks the links on the given
+// HTML files within the given root directory of the site, e.g. the files
+// changed by a commit as listed by "git diff --name-only". The paths are
+// relative to the working directory, or absolute. Paths outside the root
+// directory, of files not (or no longer) existing, and of files other than
+// HTML files are skipped. The pages linked from the files are checked, but
+// not crawled any further.
+func CrawlLocalFiles(root string, paths []string, opts CrawlOptions) (int, error) {
+	summary, err := CrawlLocalFilesContext(context.Background(), root, paths, opts)
+	if err != nil {
+		return 0, err
+	}
+	return summary.Count(StatusFailed), nil
+}
+
+// CrawlLocalFilesContext is like CrawlLocalFiles, but stops the crawl when the
+// given context is done, like CrawlPageContext. A summary of all the results
+// is returned.
+func CrawlLocalFilesContext(ctx context.Context, root string, paths []string, opts CrawlOptions) (*CrawlSummary, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	seeds := make([]*Link, 0, len(paths))
+	for _, p := range paths {
+		page, ok := localPage(absRoot, p)
+		if !ok {
+			continue
+		}
+		seeds = append(seeds, &Link{URL: page, Orig: page})
+	}
+	if len(seeds) == 0 {
+		return newCrawlSummary(), nil
+	}
+	opts.Files = os.DirFS(root)
+	opts.SeedsOnly = true
+	return crawlAndReport(ctx, newClient(&opts), seeds, opts), nil
+}
+
+// localPage returns the file: URL of the HTML file at the given path within
+// the given absolute root directory, or false if the path is outside of it, or
+// doesn't refer to an existing HTML file.
+func localPage(root, filePath string) (*url.URL, bool) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, false
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, false
+	}
+	if info, err := os.Stat(absPath); err != nil || info.IsDir() {
+		return nil, false
+	}
+	page := &url.URL{Scheme: "file", Path: "/" + filepath.ToSlash(rel)}
+	if !matchesExtension(page, localPageExtensions) {
+		return nil, false
+	}
+	return page, true
+}
+
+// fileTransport is an http.RoundTripper answering GET and HEAD requests for
+// file: URLs with the files of a file system, which is the root of the URLs'
+// paths. Requests for directories are answered with their index.html file,
+// like web servers do for static sites.
+type fileTransport struct {
+	files fs.FS
+}
+
+func (t *fileTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return fileResponse(request, http.StatusMethodNotAllowed, nil, 0), nil
+	}
+	name := fileName(request.URL)
+	info, err := fs.Stat(t.files, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, "index.html")
+		info, err = fs.Stat(t.files, name)
+	}
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) || (err == nil && info.IsDir()) {
+		return fileResponse(request, http.StatusNotFound, nil, 0), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", name, err)
+	}
+	if request.Method == http.MethodHead {
+		return fileResponse(request, http.StatusOK, nil, info.Size()), nil
+	}
+	file, err := t.files.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", name, err)
+	}
+	response := fileResponse(request, http.StatusOK, file, info.Size())
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		response.Header.Set("Content-Type", contentType)
+	}
+	return response, nil
+}
+
+// fileName returns the name of the file the given file: URL refers to within
+// the file system being its root.
+func fileName(u *url.URL) string {
+	name := strings.TrimPrefix(path.Clean("/"+u.Path), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// qualifyFileURL is like QualifyInternalURL, but for links found on the local
+// page with the given file: URL. Relative links are resolved against the
+// page's directory, which is the page itself only if it's a directory in the
+// given file system.
+func qualifyFileURL(files fs.FS, page, link *url.URL) *url.URL {
+	base := *page
+	if !strings.HasSuffix(base.Path, "/") {
+		if info, err := fs.Stat(files, fileName(page)); err == nil && info.IsDir() {
+			base.Path += "/"
+		}
+	}
+	qualified := base.ResolveReference(link)
+	qualified.Path = cleanPath(qualified.Path)
+	return qualified
+}
+
+// fileResponse creates a response to the given request with the given status
+// code and body of the given length. A nil body means an empty one.
+func fileResponse(request *http.Request, statusCode int, body io.ReadCloser, length int64) *http.Response {
+	if body == nil {
+		body = http.NoBody
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          body,
+		ContentLength: length,
+		Request:       request,
+	}
+}
@@ -0,0 +1,184 @@
+package checklinks
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileTransport(t *testing.T) {
+	files := fstest.MapFS{
+		"index.html":         {Data: []byte(`<a href="docs/">docs</a>`)},
+		"docs/index.html":    {Data: []byte(`docs`)},
+		"style.css":          {Data: []byte(`body {}`)},
+		"empty/.gitkeep":     {},
+		"img/logo image.png": {Data: []byte(`png`)},
+	}
+	opts := DefaultCrawlOptions()
+	opts.Files = files
+	client := newClient(&opts)
+	tests := []struct {
+		method      string
+		path        string
+		statusCode  int
+		contentType string
+	}{
+		{http.MethodGet, "/", http.StatusOK, "text/html; charset=utf-8"},
+		{http.MethodGet, "/docs", http.StatusOK, "text/html; charset=utf-8"},
+		{http.MethodHead, "/docs/index.html", http.StatusOK, ""},
+		{http.MethodGet, "/style.css", http.StatusOK, "text/css; charset=utf-8"},
+		{http.MethodGet, "/img/logo%20image.png", http.StatusOK, "image/png"},
+		{http.MethodGet, "/empty/", http.StatusNotFound, ""},
+		{http.MethodHead, "/missing.html", http.StatusNotFound, ""},
+		{http.MethodGet, "/../index.html", http.StatusOK, "text/html; charset=utf-8"},
+		{http.MethodPost, "/", http.StatusMethodNotAllowed, ""},
+	}
+	for _, test := range tests {
+		request, err := http.NewRequest(test.method, "file://"+test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			t.Errorf("%s %s: %v", test.method, test.path, err)
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode != test.statusCode {
+			t.Errorf("%s %s: expected status %d, got %d", test.method, test.path, test.statusCode, response.StatusCode)
+		}
+		if contentType := response.Header.Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("%s %s: expected Content-Type %q, got %q", test.method, test.path, test.contentType, contentType)
+		}
+	}
+}
+
+func TestCrawlLocal(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html": fmt.Sprintf(`<a href="about.html">about</a>
+			<a href="/docs/">docs</a>
+			<a href="missing.html">missing</a>
+			<a href="%[1]s/ok">external</a>
+			<a href="%[1]s/gone">gone</a>`, external.URL),
+		"about.html":      `<a href="docs/">docs</a><a href="img/photo.jpg">photo</a>`,
+		"docs/index.html": `<a href="../about.html">about</a><a href="setup.html">setup</a>`,
+		"img/photo.jpg":   `jpg`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, start := range []string{root, filepath.Join(root, "index.html")} {
+		var output bytes.Buffer
+		opts := DefaultCrawlOptions()
+		opts.Summary = false
+		opts.Output = &output
+		failed, err := CrawlLocal(start, opts)
+		if err != nil {
+			t.Fatalf("crawl %s: %v", start, err)
+		}
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		sort.Strings(lines)
+		expected := []string{
+			`FAIL "file:///docs/setup.html": from "file:///docs/" GET 404 Not Found file:///docs/setup.html`,
+			`FAIL "file:///missing.html": from "file:///" GET 404 Not Found file:///missing.html`,
+			fmt.Sprintf(`FAIL "%s/gone": from "file:///" HEAD 404 Not Found %s/gone`, external.URL, external.URL),
+		}
+		if start != root {
+			expected[1] = `FAIL "file:///missing.html": from "file:///index.html" GET 404 Not Found file:///missing.html`
+			expected[2] = fmt.Sprintf(`FAIL "%s/gone": from "file:///index.html" HEAD 404 Not Found %s/gone`, external.URL, external.URL)
+		}
+		sort.Strings(expected)
+		if failed != 3 || !isEqual(lines, expected) {
+			t.Errorf("crawl %s: expected 3 failures %v, got %d %v", start, expected, failed, lines)
+		}
+	}
+	if _, err := CrawlLocal(filepath.Join(root, "nonexistent"), DefaultCrawlOptions()); err == nil {
+		t.Error("expected an error crawling a nonexistent path")
+	}
+}
+
+func TestCrawlLocalFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html":      `<a href="missing.html">missing</a>`,
+		"about.html":      `<a href="docs/">docs</a><a href="img/photo.jpg">photo</a><a href="nope.html">nope</a>`,
+		"docs/index.html": `<a href="../about.html">about</a><a href="setup.html">setup</a><a href="/">home</a>`,
+		"img/photo.jpg":   `jpg`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relDocs, err := filepath.Rel(wd, filepath.Join(root, "docs", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := []string{
+		filepath.Join(root, "about.html"),
+		relDocs,
+		filepath.Join(root, "deleted.html"),
+		filepath.Join(root, "img", "photo.jpg"),
+		filepath.Join(root, "..", "outside.html"),
+	}
+
+	var output bytes.Buffer
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	opts.ReportOK = true
+	opts.Output = &output
+	failed, err := CrawlLocalFiles(root, changed, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	sort.Strings(lines)
+	expected := []string{
+		`FAIL "file:///docs/setup.html": from "file:///docs/index.html" HEAD 404 Not Found file:///docs/setup.html`,
+		`FAIL "file:///nope.html": from "file:///about.html" HEAD 404 Not Found file:///nope.html`,
+		`OK "file:///" from "file:///docs/index.html"`,
+		`OK "file:///about.html" from "file:///about.html"`,
+		`OK "file:///docs/" from "file:///about.html"`,
+		`OK "file:///docs/index.html" from "file:///docs/index.html"`,
+		`OK "file:///img/photo.jpg" from "file:///about.html"`,
+	}
+	if failed != 2 || !isEqual(lines, expected) {
+		t.Errorf("expected 2 failures in %v, got %d in %v", expected, failed, lines)
+	}
+
+	if failed, err := CrawlLocalFiles(root, []string{filepath.Join(root, "img", "photo.jpg")}, opts); failed != 0 || err != nil {
+		t.Errorf("expected nothing to be checked without HTML files, got %d failed, error %v", failed, err)
+	}
+	if _, err := CrawlLocalFiles(filepath.Join(root, "index.html"), nil, opts); err == nil {
+		t.Error("expected an error for a root that is not a directory")
+	}
+}
@@ -0,0 +1,44 @@
+package checklinks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// logger returns the Logger, or a logger discarding all records if it's nil.
+func (o *CrawlOptions) logger() *slog.Logger {
+	if o.Logger == nil {
+		return discardLogger
+	}
+	return o.Logger
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is a slog.Handler discarding all records.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// loggingTransport logs the requests sent and the responses received by the
+// wrapped transport at the debug level, including redirects.
+type loggingTransport struct {
+	transport http.RoundTripper
+	logger    *slog.Logger
+}
+
+func (t *loggingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.logger.Debug("request sent", "method", request.Method, "url", request.URL.String())
+	response, err := t.transport.RoundTrip(request)
+	if err != nil {
+		t.logger.Debug("request failed", "method", request.Method, "url", request.URL.String(), "error", err)
+		return nil, err
+	}
+	t.logger.Debug("response received", "method", request.Method, "url", request.URL.String(),
+		"status", response.StatusCode)
+	return response, nil
+}
@@ -0,0 +1,31 @@
+package checklinks
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	errNofollowLink = fmt.Errorf("%w: rel=nofollow", errIgnored)
+	errNofollowPage = fmt.Errorf("%w: page has robots nofollow", errIgnored)
+)
+
+// robotsNofollow returns true if the given document has a <meta name="robots">
+// element asking not to follow its links, i.e. with a content of "nofollow"
+// or "none" (among other, comma-separated directives), and false otherwise.
+func robotsNofollow(doc *html.Node) bool {
+	for _, element := range findElements(doc, "meta") {
+		if !strings.EqualFold(attribute(element, "name"), "robots") {
+			continue
+		}
+		for _, directive := range strings.Split(attribute(element, "content"), ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if directive == "nofollow" || directive == "none" {
+				return true
+			}
+		}
+	}
+	return false
+}
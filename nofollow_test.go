@@ -0,0 +1,87 @@
+package checklinks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestRobotsNofollow(t *testing.T) {
+	for document, expected := range map[string]bool{
+		`<meta name="robots" content="nofollow">`:          true,
+		`<meta name="ROBOTS" content="noindex, NoFollow">`: true,
+		`<meta name="robots" content="none">`:              true,
+		`<meta name="robots" content="noindex">`:           false,
+		`<meta name="googlebot" content="nofollow">`:       false,
+		`<meta name="description" content="nofollow me">`:  false,
+		`<a href="/" rel="nofollow">home</a>`:              false,
+		`<meta name="robots" content="index,follow"><p>x`:  false,
+	} {
+		doc, err := html.Parse(strings.NewReader(document))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := robotsNofollow(doc); actual != expected {
+			t.Errorf("expected nofollow of %s to be %t, was %t", document, expected, actual)
+		}
+	}
+}
+
+func TestRespectNofollow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/private" rel="nofollow">private</a>
+				<a href="/login" rel="NoFollow noopener">login</a>
+				<a href="/archive">archive</a>
+				<a href="/about">about</a>`)
+		case "/archive":
+			fmt.Fprint(w, `<meta name="robots" content="noindex, nofollow">
+				<a href="/old">old</a>
+				<a href="/login">login</a>`)
+		case "/about":
+			fmt.Fprint(w, `<a href="/login">login</a>`)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	for _, respect := range []bool{false, true} {
+		opts := DefaultCrawlOptions()
+		opts.RespectNofollow = respect
+		var checked, ignored []string
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			switch {
+			case r.Status() == StatusOK:
+				checked = append(checked, r.Link.URL.Path)
+			case errors.Is(r.Err, errNofollowLink), errors.Is(r.Err, errNofollowPage):
+				ignored = append(ignored, r.Link.Orig.Path+" -> "+r.Link.URL.Path)
+			default:
+				t.Errorf("unexpected result %v", r)
+			}
+		})
+		sort.Strings(checked)
+		sort.Strings(ignored)
+		expectedChecked := []string{"/", "/about", "/archive", "/login", "/old", "/private"}
+		var expectedIgnored []string
+		if respect {
+			// /login is linked without nofollow from /about.
+			expectedChecked = []string{"/", "/about", "/archive", "/login"}
+			expectedIgnored = []string{"/ -> /login", "/ -> /private", "/archive -> /login", "/archive -> /old"}
+		}
+		if !isEqual(checked, expectedChecked) {
+			t.Errorf("expected links %v to be checked (respect nofollow: %t), got %v", expectedChecked, respect, checked)
+		}
+		if !isEqual(ignored, expectedIgnored) {
+			t.Errorf("expected links %v to be ignored (respect nofollow: %t), got %v", expectedIgnored, respect, ignored)
+		}
+	}
+}
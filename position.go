@@ -0,0 +1,71 @@
+package checklinks
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// position is the line and column (starting at 1) of a tag in an HTML
+// document's source. The column counts characters, not bytes.
+type position struct {
+	line, column int
+}
+
+// positionKey identifies an attribute of an element with a link by its tag
+// name, attribute name, and value.
+type positionKey struct {
+	tag, attr, value string
+}
+
+// positionIndex records the positions of the start tags of an HTML document's
+// elements by their attributes, since the nodes of a parsed document don't
+// have positions. The positions of attributes with the same tag, name, and
+// value are recorded in the order of the source.
+type positionIndex map[positionKey][]position
+
+// indexPositions tokenizes the given HTML source, and records the position of
+// every start tag for each of its attributes.
+func indexPositions(src []byte) positionIndex {
+	index := make(positionIndex)
+	z := html.NewTokenizer(bytes.NewReader(src))
+	pos := position{line: 1, column: 1}
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return index
+		}
+		raw := z.Raw()
+		if tt == html.StartTagToken || tt == html.SelfClosingTagToken {
+			token := z.Token()
+			for _, attr := range token.Attr {
+				key := positionKey{token.Data, attr.Key, attr.Val}
+				index[key] = append(index[key], pos)
+			}
+		}
+		for len(raw) > 0 {
+			r, size := utf8.DecodeRune(raw)
+			raw = raw[size:]
+			if r == '\n' {
+				pos.line++
+				pos.column = 1
+			} else {
+				pos.column++
+			}
+		}
+	}
+}
+
+// next returns the position of the next element with the given tag and
+// attribute value, and removes it from the index. False is returned if there
+// is no such element (left) in the index.
+func (index positionIndex) next(tag, attr, value string) (position, bool) {
+	key := positionKey{tag, attr, value}
+	positions := index[key]
+	if len(positions) == 0 {
+		return position{}, false
+	}
+	index[key] = positions[1:]
+	return positions[0], true
+}
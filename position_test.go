@@ -0,0 +1,75 @@
+package checklinks
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const positionDocument = `<!DOCTYPE html>
+<html>
+<head>
+  <link rel="stylesheet" href="/style.css">
+</head>
+<body>
+  <p>Grüezi, <a href="/about">about</a> and
+  <a
+     href="/missing">missing</a></p>
+  <img src="/logo.png" srcset="/logo.png 1x, /logo@2x.png 2x"/>
+  <a href="/about">about again</a>
+  <!-- <a href="/commented">commented</a> -->
+</body>
+</html>
+`
+
+func TestIndexPositions(t *testing.T) {
+	index := indexPositions([]byte(positionDocument))
+	tests := []struct {
+		tag, attr, value string
+		expected         position
+	}{
+		{"link", "href", "/style.css", position{4, 3}},
+		{"a", "href", "/about", position{7, 14}},
+		{"a", "href", "/missing", position{8, 3}},
+		{"img", "srcset", "/logo.png 1x, /logo@2x.png 2x", position{10, 3}},
+		{"a", "href", "/about", position{11, 3}},
+	}
+	for _, test := range tests {
+		actual, ok := index.next(test.tag, test.attr, test.value)
+		if !ok || actual != test.expected {
+			t.Errorf("expected <%s %s=%q> at %v, got %v (found: %t)",
+				test.tag, test.attr, test.value, test.expected, actual, ok)
+		}
+	}
+	if _, ok := index.next("a", "href", "/about"); ok {
+		t.Errorf("expected no more positions of /about")
+	}
+	if _, ok := index.next("a", "href", "/commented"); ok {
+		t.Errorf("expected no position for a link in a comment")
+	}
+}
+
+func TestExtractLinksPositions(t *testing.T) {
+	page := mustParse("https://example.com/")
+	root, _ := html.Parse(strings.NewReader(positionDocument))
+	attributes := append(append([]TagAttribute{}, LinkAttributes...), ResourceAttributes...)
+	links, _ := extractLinks(root, indexPositions([]byte(positionDocument)), nil, page, attributes)
+	var actual []string
+	for _, link := range links {
+		actual = append(actual, fmt.Sprintf("%s %d:%d", link.URL, link.Line, link.Column))
+	}
+	expected := []string{
+		"/about 7:14",
+		"/missing 8:3",
+		"/about 11:3",
+		"/style.css 4:3",
+		"/logo.png 10:3",
+		"/logo.png 10:3",
+		"/logo@2x.png 10:3",
+	}
+	if !isEqual(actual, expected) {
+		t.Errorf("expected links %v, got %v", expected, actual)
+	}
+}
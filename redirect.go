@@ -0,0 +1,126 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Redirect describes the redirects followed for a link's request.
+type Redirect struct {
+	// StatusCode is the status code of the first redirect response, e.g. 301,
+	// or 0 for a redirect by a <meta http-equiv="refresh"> element.
+	StatusCode int
+
+	// URL is the final URL reached after following all the redirects.
+	URL *url.URL
+}
+
+// IsPermanent returns true if the redirect's status code indicates that the
+// link has moved permanently (301 or 308), i.e. the link should be updated.
+func (r *Redirect) IsPermanent() bool {
+	return r.StatusCode == http.StatusMovedPermanently || r.StatusCode == http.StatusPermanentRedirect
+}
+
+func (r *Redirect) String() string {
+	if r.StatusCode == 0 {
+		return fmt.Sprintf("meta refresh redirect to %s", r.URL)
+	}
+	kind := "temporary"
+	if r.IsPermanent() {
+		kind = "permanent"
+	}
+	return fmt.Sprintf("%s redirect (%d) to %s", kind, r.StatusCode, r.URL)
+}
+
+// redirectWarning reports a permanent redirect as a warning.
+type redirectWarning struct {
+	redirect *Redirect
+}
+
+func (e *redirectWarning) Error() string {
+	return fmt.Sprintf("%v: %v", errWarning, e.redirect)
+}
+
+func (e *redirectWarning) Unwrap() error {
+	return errWarning
+}
+
+// redirectOf returns the redirects followed to get the given response, or nil
+// if its request hasn't been redirected.
+func redirectOf(response *http.Response) *Redirect {
+	if response == nil || response.Request == nil || response.Request.Response == nil {
+		return nil
+	}
+	first := response.Request.Response
+	for first.Request != nil && first.Request.Response != nil {
+		first = first.Request.Response
+	}
+	return &Redirect{StatusCode: first.StatusCode, URL: response.Request.URL}
+}
+
+// withRedirect records the given redirect in the given successful result if
+// the options ask to warn about redirects. Permanent redirects turn the result
+// into a warning.
+func withRedirect(result *Result, redirect *Redirect, opts *CrawlOptions) *Result {
+	if !opts.WarnRedirects || redirect == nil || result.Err != nil {
+		return result
+	}
+	result.Redirect = redirect
+	if redirect.IsPermanent() {
+		result.Err = &redirectWarning{redirect}
+	}
+	return result
+}
+
+// htmlSpace are the characters considered white space by the HTML standard.
+const htmlSpace = " \t\n\r\f"
+
+// ParseMetaRefresh returns the URL in the given content attribute of a <meta
+// http-equiv="refresh"> element, e.g. "0; url='/new-page'", following the
+// parsing rules of the HTML standard: the "url=" prefix is case-insensitive and
+// optional, and the URL may be quoted. False is returned if the content is
+// malformed, or only refreshes the page itself.
+func ParseMetaRefresh(content string) (string, bool) {
+	s := strings.TrimLeft(content, htmlSpace)
+	time := strings.TrimLeft(s, "0123456789")
+	if time == s && !strings.HasPrefix(s, ".") {
+		return "", false
+	}
+	s = strings.TrimLeft(time, "0123456789.")
+	if s == "" || !strings.ContainsAny(s[:1], htmlSpace+";,") {
+		return "", false
+	}
+	s = strings.TrimLeft(s, htmlSpace)
+	if strings.HasPrefix(s, ";") || strings.HasPrefix(s, ",") {
+		s = strings.TrimLeft(s[1:], htmlSpace)
+	}
+	if len(s) >= 3 && strings.EqualFold(s[:3], "url") {
+		if rest := strings.TrimLeft(s[3:], htmlSpace); strings.HasPrefix(rest, "=") {
+			s = strings.TrimLeft(rest[1:], htmlSpace)
+		}
+	}
+	if strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`) {
+		quote := s[:1]
+		s = s[1:]
+		if end := strings.Index(s, quote); end >= 0 {
+			s = s[:end]
+		}
+	}
+	s = strings.TrimRight(s, htmlSpace)
+	return s, s != ""
+}
+
+// extractMetaRefresh returns the URL the given document redirects to using a
+// <meta http-equiv="refresh"> element, or false if it doesn't.
+func extractMetaRefresh(doc *html.Node) (string, bool) {
+	for _, element := range findElements(doc, "meta") {
+		if strings.EqualFold(attribute(element, "http-equiv"), "refresh") {
+			return ParseMetaRefresh(attribute(element, "content"))
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,130 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWarnRedirects(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/moved-again", http.StatusMovedPermanently)
+		case "/moved-again":
+			http.Redirect(w, r, "/target", http.StatusFound)
+		case "/target":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+	external.URL = strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<a href="/old">old</a>
+				<a href="/temp">temp</a>
+				<a href="%s/moved">external</a>`, external.URL)
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusPermanentRedirect)
+		case "/temp":
+			http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+		case "/new":
+			fmt.Fprint(w, "new")
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+
+	for _, warn := range []bool{false, true} {
+		opts.WarnRedirects = warn
+		var reported []string
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			if r.Status() != StatusOK || r.Redirect != nil {
+				reported = append(reported, r.String())
+			}
+		})
+		sort.Strings(reported)
+		var expected []string
+		if warn {
+			expected = []string{
+				fmt.Sprintf(`OK "%s/temp": from "%s/" temporary redirect (307) to %s/new`, srv.URL, srv.URL, srv.URL),
+				fmt.Sprintf(`WARN "%s/old": from "%s/" warning: permanent redirect (308) to %s/new`, srv.URL, srv.URL, srv.URL),
+				fmt.Sprintf(`WARN "%s/moved": from "%s/" warning: permanent redirect (301) to %s/target`, external.URL, srv.URL, external.URL),
+			}
+		}
+		if !isEqual(reported, expected) {
+			t.Errorf("expected %v (warn: %v), got %v", expected, warn, reported)
+		}
+	}
+}
+
+var metaRefreshTests = []struct {
+	content string
+	url     string
+	ok      bool
+}{
+	{"0; url=https://example.com/", "https://example.com/", true},
+	{"0;URL=/new-page", "/new-page", true},
+	{"5 ; Url = '/quoted page.html' ", "/quoted page.html", true},
+	{`0; url="/double"; ignored`, "/double", true},
+	{"0, url=/comma", "/comma", true},
+	{"0 /no-prefix", "/no-prefix", true},
+	{"1.5; url=/fraction", "/fraction", true},
+	{"  3;   url=/spaces   ", "/spaces", true},
+	{"0; url='/unterminated", "/unterminated", true},
+	{"30", "", false},
+	{"0;", "", false},
+	{"0; url=", "", false},
+	{"url=/no-time", "", false},
+	{"0x; url=/malformed", "", false},
+	{"", "", false},
+}
+
+func TestParseMetaRefresh(t *testing.T) {
+	for _, testCase := range metaRefreshTests {
+		u, ok := ParseMetaRefresh(testCase.content)
+		if u != testCase.url || ok != testCase.ok {
+			t.Errorf("expected ParseMetaRefresh(%q) to be %q, %v, was %q, %v",
+				testCase.content, testCase.url, testCase.ok, u, ok)
+		}
+	}
+}
+
+func TestMetaRefresh(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/old">old</a>`)
+		case "/old":
+			fmt.Fprint(w, `<html><head><META HTTP-EQUIV="Refresh" CONTENT="0; URL='/new'"></head></html>`)
+		case "/new":
+			fmt.Fprint(w, `<a href="/missing">missing</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.WarnRedirects = true
+
+	var results []string
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		results = append(results, strings.ReplaceAll(r.String(), srv.URL, ""))
+	})
+	sort.Strings(results)
+	expected := []string{
+		`FAIL "/missing": from "/new" GET 404 Not Found /missing`,
+		`OK "/" from "/"`,
+		`OK "/new" <meta> from "/old"`,
+		`OK "/old": from "/" meta refresh redirect to /new`,
+	}
+	if !isEqual(results, expected) {
+		t.Errorf("expected results %v, got %v", expected, results)
+	}
+}
@@ -0,0 +1,40 @@
+package checklinks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseResolve parses a host name override given as "host:ip", e.g.
+// "www.example.com:10.0.0.5" or "www.example.com:[::1]", like curl's --resolve
+// option, but without a port. The host name is returned in lower case. An
+// error is returned if the override is malformed.
+func ParseResolve(resolve string) (host, ip string, err error) {
+	host, ip, ok := strings.Cut(resolve, ":")
+	host = strings.ToLower(strings.TrimSpace(host))
+	ip = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(ip), "["), "]")
+	if !ok || host == "" || strings.ContainsAny(host, "/[] ") {
+		return "", "", fmt.Errorf(`resolve "%s": expected "host:ip"`, resolve)
+	}
+	if net.ParseIP(ip) == nil {
+		return "", "", fmt.Errorf(`resolve "%s": invalid IP address "%s"`, resolve, ip)
+	}
+	return host, ip, nil
+}
+
+// resolvingDialer returns a function dialing the given address, whose host is
+// replaced by the IP address it's mapped to in the given overrides (if any).
+// The port is kept, and so is the host name used for TLS and in the requests.
+func resolvingDialer(resolve map[string]string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			if ip, ok := resolve[strings.ToLower(host)]; ok {
+				addr = net.JoinHostPort(ip, port)
+			}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,66 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		resolve string
+		host    string
+		ip      string
+		ok      bool
+	}{
+		{"www.example.com:10.0.0.5", "www.example.com", "10.0.0.5", true},
+		{"WWW.Example.com:127.0.0.1", "www.example.com", "127.0.0.1", true},
+		{"www.example.com:[::1]", "www.example.com", "::1", true},
+		{"www.example.com:::1", "www.example.com", "::1", true},
+		{"www.example.com", "", "", false},
+		{":10.0.0.5", "", "", false},
+		{"www.example.com:staging", "", "", false},
+		{"www.example.com:10.0.0.5:443", "", "", false},
+	}
+	for _, test := range tests {
+		host, ip, err := ParseResolve(test.resolve)
+		if (err == nil) != test.ok || host != test.host || ip != test.ip {
+			t.Errorf("parse %q: expected %q, %q (ok: %t), got %q, %q, %v", test.resolve, test.host, test.ip, test.ok, host, ip, err)
+		}
+	}
+}
+
+func TestCrawlResolve(t *testing.T) {
+	var mu sync.Mutex
+	var hits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, r.Host+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			fmt.Fprintf(w, `<a href="http://%s/about">about</a>`, r.Host)
+		}
+	}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+
+	// The site is checked on its real host name, which doesn't resolve to
+	// the test server.
+	opts := DefaultCrawlOptions()
+	opts.Resolve = map[string]string{"www.example.com": "127.0.0.1"}
+	statuses := make(map[string]Status)
+	CrawlPageFunc(mustParse("http://www.example.com:"+port+"/"), opts, func(r *Result) {
+		statuses[r.Link.URL.String()] = r.Status()
+	})
+	about := "http://www.example.com:" + port + "/about"
+	if statuses[about] != StatusOK {
+		t.Errorf("expected %s to be OK, got %v", about, statuses)
+	}
+	expected := []string{"www.example.com:" + port + "/", "www.example.com:" + port + "/about"}
+	if !isEqual(hits, expected) {
+		t.Errorf("expected requests %v, got %v", expected, hits)
+	}
+}
@@ -0,0 +1,90 @@
+package checklinks
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultRetryDelay is waited before retrying a request if the response
+	// has no valid Retry-After header.
+	defaultRetryDelay = time.Second
+
+	// maxRetryDelay is the longest Retry-After delay waited for. Responses
+	// asking to wait longer are not retried.
+	maxRetryDelay = time.Minute
+)
+
+// retryTransport retries the requests that are answered with 429 Too Many
+// Requests or 503 Service Unavailable up to the given number of times, after
+// waiting as long as the response's Retry-After header says.
+type retryTransport struct {
+	transport http.RoundTripper
+	retries   int
+	logger    *slog.Logger
+}
+
+func (t *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		response, err := t.transport.RoundTrip(request)
+		if err != nil || attempt >= t.retries || !isRetryable(request, response) {
+			return response, err
+		}
+		delay, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			delay = defaultRetryDelay
+		}
+		if deadline, ok := request.Context().Deadline(); delay > maxRetryDelay || (ok && time.Now().Add(delay).After(deadline)) {
+			return response, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+		response.Body.Close()
+		t.logger.Debug("request retried", "method", request.Method, "url", request.URL.String(),
+			"status", response.StatusCode, "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		}
+	}
+}
+
+// isRetryable returns true if the given response to the given request asks to
+// retry later, and the request has no body that would need to be sent again.
+func isRetryable(request *http.Request, response *http.Response) bool {
+	if request.Body != nil && request.Body != http.NoBody {
+		return false
+	}
+	return response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date, and returns the delay relative to the
+// given time. Dates in the past result in no delay. False is returned if the
+// value is invalid.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
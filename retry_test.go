@@ -0,0 +1,84 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, time.April, 12, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Tue, 12 Apr 2022 10:00:30 GMT", 30 * time.Second, true},
+		{"Tue, 12 Apr 2022 09:59:00 GMT", 0, true},
+		{"", 0, false},
+		{"-5", 0, false},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		delay, ok := parseRetryAfter(test.value, now)
+		if delay != test.delay || ok != test.ok {
+			t.Errorf("parse %q: expected %v (%v), got %v (%v)", test.value, test.delay, test.ok, delay, ok)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/limited">limited</a><a href="/unavailable">unavailable</a>
+				<a href="/later">later</a>`)
+		case "/limited":
+			if n == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		case "/unavailable":
+			w.Header().Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/later":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+
+	for _, retries := range []int{0, 2} {
+		requests = make(map[string]int)
+		opts.Retries = retries
+		statusCodes := make(map[string]int)
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			statusCodes[r.Link.URL.Path] = r.StatusCode
+		})
+		expectedCodes := map[string]int{"/": 200, "/limited": 200, "/unavailable": 503, "/later": 429}
+		expectedRequests := map[string]int{"/": 1, "/limited": 2, "/unavailable": 3, "/later": 1}
+		if retries == 0 {
+			expectedCodes["/limited"] = 429
+			expectedRequests["/limited"] = 1
+			expectedRequests["/unavailable"] = 1
+		}
+		if fmt.Sprint(statusCodes) != fmt.Sprint(expectedCodes) {
+			t.Errorf("expected status codes %v with %d retries, got %v", expectedCodes, retries, statusCodes)
+		}
+		if fmt.Sprint(requests) != fmt.Sprint(expectedRequests) {
+			t.Errorf("expected requests %v with %d retries, got %v", expectedRequests, retries, requests)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package checklinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// sarifVersion and sarifSchema identify the version of SARIF (Static Analysis
+// Results Interchange Format) written by the SARIFFormatter.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// The rules of the SARIF results, i.e. the kinds of problems reported.
+const (
+	sarifRuleBroken  = "broken-link"
+	sarifRuleWarning = "link-warning"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifProperties struct {
+	Internal bool `json:"internal"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFFormatter writes the results as a SARIF log, which is understood by
+// code scanning tools, e.g. to show the broken links in GitHub's security tab.
+// Failed links are reported as errors, and warnings as such, located at the
+// page the link was found on, and at the link's position in the page's source
+// if known. The results' properties tell whether the link is internal (see
+// Result.Internal). Links that succeeded or haven't been checked are not
+// reported. A result with multiple Sources becomes a SARIF result for every
+// source. The log is written when the formatter is flushed.
+type SARIFFormatter struct {
+	// Root is the path of the local files' directory (see CrawlLocal)
+	// relative to the repository, e.g. "public", so that the locations of
+	// local pages refer to the repository's files. The locations of other
+	// pages are their URLs.
+	Root string
+
+	w       io.Writer
+	results []*sarifResult
+}
+
+// NewSARIFFormatter creates a SARIFFormatter writing to the given writer.
+func NewSARIFFormatter(w io.Writer) *SARIFFormatter {
+	return &SARIFFormatter{w: w, results: make([]*sarifResult, 0)}
+}
+
+// Format adds the given result, if failed or a warning, as a SARIF result for
+// every page the link was found on.
+func (f *SARIFFormatter) Format(result *Result) error {
+	var rule, level string
+	switch result.Status() {
+	case StatusFailed:
+		rule, level = sarifRuleBroken, "error"
+	case StatusWarning:
+		rule, level = sarifRuleWarning, "warning"
+	default:
+		return nil
+	}
+	message := fmt.Sprintf("%s: %v", result.Link.URL, result.Err)
+	for _, source := range result.sources() {
+		location := sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: f.location(source)},
+		}
+		// The link's position refers to the page it was found on first,
+		// not to the other pages linking to it.
+		if result.Link.Line > 0 && source.String() == result.Link.Orig.String() {
+			location.Region = &sarifRegion{
+				StartLine:   result.Link.Line,
+				StartColumn: result.Link.Column,
+			}
+		}
+		f.results = append(f.results, &sarifResult{
+			RuleID:     rule,
+			Level:      level,
+			Message:    sarifMessage{Text: message},
+			Locations:  []sarifLocation{{PhysicalLocation: location}},
+			Properties: sarifProperties{Internal: result.Internal},
+		})
+	}
+	return nil
+}
+
+// location returns the URI of the given page for the SARIF log: the path of
+// a local page relative to the repository, or the URL of other pages.
+func (f *SARIFFormatter) location(page *url.URL) string {
+	if page.Scheme != "file" {
+		return page.String()
+	}
+	name := fileName(page)
+	if name == "." || strings.HasSuffix(page.Path, "/") {
+		name = path.Join(name, "index.html")
+	}
+	if root := strings.TrimPrefix(path.Clean("/"+f.Root), "/"); root != "" {
+		name = path.Join(root, name)
+	}
+	return (&url.URL{Path: name}).String()
+}
+
+// Flush writes the SARIF log.
+func (f *SARIFFormatter) Flush() error {
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "checklinks",
+				InformationURI: "https://github.com/patrickbucher/checklinks",
+				Rules: []sarifRule{
+					{ID: sarifRuleBroken, ShortDescription: sarifMessage{Text: "Broken link"}},
+					{ID: sarifRuleWarning, ShortDescription: sarifMessage{Text: "Problematic link"}},
+				},
+			}},
+			Results: f.results,
+		}},
+	}
+	encoder := json.NewEncoder(f.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
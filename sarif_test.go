@@ -0,0 +1,157 @@
+package checklinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+const expectedSARIF = `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "checklinks",
+          "informationUri": "https://github.com/patrickbucher/checklinks",
+          "rules": [
+            {
+              "id": "broken-link",
+              "shortDescription": {
+                "text": "Broken link"
+              }
+            },
+            {
+              "id": "link-warning",
+              "shortDescription": {
+                "text": "Problematic link"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "broken-link",
+          "level": "error",
+          "message": {
+            "text": "https://github.com/patrickbucher/missing: GET 404 Not Found https://github.com/patrickbucher/missing"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "https://paedubucher.ch/"
+                }
+              }
+            }
+          ],
+          "properties": {
+            "internal": false
+          }
+        },
+        {
+          "ruleId": "broken-link",
+          "level": "error",
+          "message": {
+            "text": "https://no.such.host/: dial tcp: lookup no.such.host, port 443: no such host"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "https://paedubucher.ch/about/"
+                }
+              }
+            }
+          ],
+          "properties": {
+            "internal": false
+          }
+        }
+      ]
+    }
+  ]
+}
+`
+
+func TestSARIFFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewSARIFFormatter(&buf)
+	for _, result := range formatResults {
+		if err := formatter.Format(result); err != nil {
+			t.Fatalf("format result: %v", err)
+		}
+	}
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if buf.String() != expectedSARIF {
+		t.Errorf("expected SARIF output\n%s\ngot\n%s", expectedSARIF, buf.String())
+	}
+}
+
+func TestSARIFLocalLocations(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewSARIFFormatter(&buf)
+	formatter.Root = "./public"
+	results := []*Result{
+		{Err: &statusError{"GET", 404, "file:///missing.html"}, Link: &Link{URL: mustParse("file:///missing.html"), Orig: mustParse("file:///")}},
+		{Err: errMixedContent, Link: &Link{URL: mustParse("http://example.com/app.js"), Orig: mustParse("file:///docs/setup%20guide.html")}},
+		{Err: &statusError{"GET", 404, "file:///docs/x.html"}, Link: &Link{URL: mustParse("file:///docs/x.html"), Orig: mustParse("file:///docs/")},
+			Sources: []*url.URL{mustParse("file:///docs/"), mustParse("file:///about.html")}},
+		{Link: &Link{URL: mustParse("file:///ok.html"), Orig: mustParse("file:///")}},
+	}
+	for _, result := range results {
+		formatter.Format(result)
+	}
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("parse SARIF output: %v", err)
+	}
+	var locations, levels []string
+	for _, result := range log.Runs[0].Results {
+		locations = append(locations, result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		levels = append(levels, result.Level)
+	}
+	expectedLocations := []string{"public/index.html", "public/docs/setup%20guide.html", "public/docs/index.html", "public/about.html"}
+	if !isEqual(locations, expectedLocations) {
+		t.Errorf("expected locations %v, got %v", expectedLocations, locations)
+	}
+	if expectedLevels := []string{"error", "warning", "error", "error"}; !isEqual(levels, expectedLevels) {
+		t.Errorf("expected levels %v, got %v", expectedLevels, levels)
+	}
+}
+
+func TestSARIFRegions(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewSARIFFormatter(&buf)
+	formatter.Format(&Result{
+		Err:     &statusError{"GET", 404, "file:///missing.html"},
+		Link:    &Link{URL: mustParse("file:///missing.html"), Orig: mustParse("file:///docs/"), Line: 12, Column: 5},
+		Sources: []*url.URL{mustParse("file:///docs/"), mustParse("file:///about.html")},
+	})
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("parse SARIF output: %v", err)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	region := results[0].Locations[0].PhysicalLocation.Region
+	if region == nil || *region != (sarifRegion{StartLine: 12, StartColumn: 5}) {
+		t.Errorf("expected region 12:5 on the page the link was found on, got %+v", region)
+	}
+	if region := results[1].Locations[0].PhysicalLocation.Region; region != nil {
+		t.Errorf("expected no region on the other source, got %+v", region)
+	}
+}
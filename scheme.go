@@ -0,0 +1,124 @@
+package checklinks
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// Scheme classifies a link by the scheme of its URL.
+type Scheme int
+
+const (
+	// SchemeHTTP indicates an http or https URL, or a URL without a scheme,
+	// which is relative to the page it was found on. Only such links are
+	// crawled and checked.
+	SchemeHTTP Scheme = iota
+
+	// SchemeMailto indicates an e-mail address, e.g. mailto:info@example.com.
+	SchemeMailto
+
+	// SchemeTel indicates a phone number, e.g. tel:+41-31-123-45-67.
+	SchemeTel
+
+	// SchemeJavaScript indicates inline code, e.g. javascript:void(0).
+	SchemeJavaScript
+
+	// SchemeData indicates inline data, e.g. data:image/png;base64,...
+	SchemeData
+
+	// SchemeOther indicates any other scheme, e.g. ftp.
+	SchemeOther
+)
+
+// ClassifyScheme classifies the given URL by its scheme.
+func ClassifyScheme(u *url.URL) Scheme {
+	// url.Parse already converts the scheme to lower case.
+	switch u.Scheme {
+	case "http", "https", "":
+		return SchemeHTTP
+	case "mailto":
+		return SchemeMailto
+	case "tel":
+		return SchemeTel
+	case "javascript":
+		return SchemeJavaScript
+	case "data":
+		return SchemeData
+	default:
+		return SchemeOther
+	}
+}
+
+// String returns the scheme as written in URLs, or "other" for SchemeOther.
+func (s Scheme) String() string {
+	switch s {
+	case SchemeHTTP:
+		return "http"
+	case SchemeMailto:
+		return "mailto"
+	case SchemeTel:
+		return "tel"
+	case SchemeJavaScript:
+		return "javascript"
+	case SchemeData:
+		return "data"
+	case SchemeOther:
+		return "other"
+	default:
+		return fmt.Sprintf("Scheme(%d)", int(s))
+	}
+}
+
+// schemeError reports a link that is ignored because of its URL's scheme.
+type schemeError struct {
+	scheme string
+}
+
+// newSchemeError creates a schemeError for the given URL.
+func newSchemeError(u *url.URL) *schemeError {
+	return &schemeError{scheme: u.Scheme}
+}
+
+func (e *schemeError) Error() string {
+	return fmt.Sprintf("%v: %s link", errIgnored, e.scheme)
+}
+
+func (e *schemeError) Unwrap() error {
+	return errIgnored
+}
+
+// ValidateMailto checks the syntax of the e-mail addresses of the given
+// mailto: URL, which are given before the query or in its to, cc, and bcc
+// fields. An error is returned if there is no address, or if any address is
+// malformed. It's not checked whether the mailboxes exist.
+func ValidateMailto(u *url.URL) error {
+	to, err := url.PathUnescape(u.Opaque)
+	if err != nil {
+		return fmt.Errorf("invalid mailto link: %w", err)
+	}
+	addresses := strings.Split(to, ",")
+	query := u.Query()
+	for _, field := range []string{"to", "cc", "bcc"} {
+		for _, value := range query[field] {
+			addresses = append(addresses, strings.Split(value, ",")...)
+		}
+	}
+	var found bool
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid e-mail address %q: %w", address, err)
+		}
+		found = true
+	}
+	if !found {
+		return errors.New("mailto link without e-mail address")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package checklinks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var classifySchemeTests = []struct {
+	address string
+	scheme  Scheme
+}{
+	{"/about/", SchemeHTTP},
+	{"http://example.com/", SchemeHTTP},
+	{"HTTPS://example.com/", SchemeHTTP},
+	{"mailto:info@example.com", SchemeMailto},
+	{"tel:+41-31-123-45-67", SchemeTel},
+	{"javascript:void(0)", SchemeJavaScript},
+	{"data:image/png;base64,iVBORw0KGgo=", SchemeData},
+	{"ftp://ftp.example.com/file.txt", SchemeOther},
+}
+
+func TestClassifyScheme(t *testing.T) {
+	for _, testCase := range classifySchemeTests {
+		if actual := ClassifyScheme(mustParse(testCase.address)); actual != testCase.scheme {
+			t.Errorf("expected %s to be classified as %v, was %v", testCase.address, testCase.scheme, actual)
+		}
+	}
+}
+
+func TestCrawlIgnoresSchemes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="mailto:info@example.com">mail</a>
+			<a href="tel:+41-31-123-45-67">phone</a>
+			<a href="javascript:void(0)">script</a>
+			<a href="ftp://ftp.example.com/">ftp</a>
+			<a href="http://[::1">malformed</a>`)
+	}))
+	defer srv.Close()
+
+	messages := make(map[Scheme]string)
+	var malformed []*Result
+	CrawlPageFunc(mustParse(srv.URL), DefaultCrawlOptions(), func(r *Result) {
+		switch r.Status() {
+		case StatusIgnored:
+			messages[r.Scheme] = r.Err.Error()
+		case StatusFailed:
+			malformed = append(malformed, r)
+		}
+	})
+	expected := map[Scheme]string{
+		SchemeMailto:     "ignored: mailto link",
+		SchemeTel:        "ignored: tel link",
+		SchemeJavaScript: "ignored: javascript link",
+		SchemeOther:      "ignored: ftp link",
+	}
+	for scheme, message := range expected {
+		if messages[scheme] != message {
+			t.Errorf("expected %v link to be ignored with %q, got %q", scheme, message, messages[scheme])
+		}
+	}
+	if len(malformed) != 1 || errors.Is(malformed[0].Err, errIgnored) {
+		t.Errorf("expected the malformed link to fail, got %v", malformed)
+	}
+}
+
+var validateMailtoTests = []struct {
+	address string
+	valid   bool
+}{
+	{"mailto:info@example.com", true},
+	{"mailto:info@example.com,sales@example.com", true},
+	{"mailto:John%20Doe%20%3Cjohn@example.com%3E", true},
+	{"mailto:info@example.com?subject=Hello", true},
+	{"mailto:?to=info@example.com&cc=sales@example.com", true},
+	{"mailto:john(at)example.com", false},
+	{"mailto:john.example.com", false},
+	{"mailto:info@example.com,john@", false},
+	{"mailto:info@example.com?cc=john", false},
+	{"mailto:?subject=Hello", false},
+	{"mailto:", false},
+}
+
+func TestValidateMailto(t *testing.T) {
+	for _, testCase := range validateMailtoTests {
+		err := ValidateMailto(mustParse(testCase.address))
+		if testCase.valid && err != nil {
+			t.Errorf("expected %s to be valid, got %v", testCase.address, err)
+		}
+		if !testCase.valid && err == nil {
+			t.Errorf("expected %s to be invalid", testCase.address)
+		}
+	}
+}
+
+func TestCheckMailto(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="mailto:info@example.com">ok</a><a href="mailto:john(at)example.com">typo</a>`)
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.CheckMailto = true
+
+	statuses := make(map[string]Status)
+	CrawlPageFunc(mustParse(srv.URL), opts, func(r *Result) {
+		if r.Scheme == SchemeMailto {
+			statuses[r.Link.URL.String()] = r.Status()
+		}
+	})
+	expected := map[string]Status{
+		"mailto:info@example.com":    StatusOK,
+		"mailto:john(at)example.com": StatusFailed,
+	}
+	for address, status := range expected {
+		if statuses[address] != status {
+			t.Errorf("expected %s to be reported as %v, got %v", address, status, statuses[address])
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package checklinks
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Selector is a list of simple CSS selectors, e.g. "main, .content, #docs",
+// which matches an element if any of its selectors does (see ParseSelector).
+// An empty Selector matches no element.
+type Selector []simpleSelector
+
+// simpleSelector matches the elements with the given tag name (if any), the
+// given id (if any), and all of the given classes.
+type simpleSelector struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+// ParseSelector parses the given list of comma-separated selectors, each of
+// which consists of an optional tag name, followed by any number of classes
+// (".content") and an optional id ("#main"), e.g. "main", "div.content", or
+// "#docs". Other selectors (e.g. combinators or attribute selectors) are not
+// supported.
+func ParseSelector(s string) (Selector, error) {
+	selector := make(Selector, 0)
+	for _, part := range strings.Split(s, ",") {
+		simple, err := parseSimpleSelector(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %w", s, err)
+		}
+		selector = append(selector, simple)
+	}
+	return selector, nil
+}
+
+// parseSimpleSelector parses a single selector such as "div.content#main".
+func parseSimpleSelector(s string) (simpleSelector, error) {
+	var simple simpleSelector
+	if s == "" {
+		return simple, fmt.Errorf("empty selector")
+	}
+	end := strings.IndexAny(s, ".#")
+	if end < 0 {
+		end = len(s)
+	}
+	simple.tag, s = strings.ToLower(s[:end]), s[end:]
+	if !isSelectorName(simple.tag) && simple.tag != "" {
+		return simple, fmt.Errorf("invalid tag name %q", simple.tag)
+	}
+	for s != "" {
+		prefix := s[0]
+		end := strings.IndexAny(s[1:], ".#")
+		if end < 0 {
+			end = len(s) - 1
+		}
+		name := s[1 : end+1]
+		s = s[end+1:]
+		if !isSelectorName(name) {
+			return simple, fmt.Errorf("invalid name %q after %q", name, prefix)
+		}
+		if prefix == '#' {
+			if simple.id != "" {
+				return simple, fmt.Errorf("multiple ids")
+			}
+			simple.id = name
+		} else {
+			simple.classes = append(simple.classes, name)
+		}
+	}
+	return simple, nil
+}
+
+// isSelectorName returns true if the given tag name, class, or id consists of
+// letters, digits, hyphens, and underscores only, and false otherwise.
+func isSelectorName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches returns true if the given node is an element matching one of the
+// selectors, and false otherwise.
+func (s Selector) Matches(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	for _, simple := range s {
+		if simple.matches(node) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if the given node is, or is within, an element
+// matching the selector, and false otherwise.
+func (s Selector) contains(node *html.Node) bool {
+	for n := node; n != nil; n = n.Parent {
+		if s.Matches(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s simpleSelector) matches(node *html.Node) bool {
+	if s.tag != "" && node.Data != s.tag {
+		return false
+	}
+	if s.id != "" && attribute(node, "id") != s.id {
+		return false
+	}
+	classes := strings.Fields(attribute(node, "class"))
+	for _, class := range s.classes {
+		if !slices.Contains(classes, class) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseSelector(t *testing.T) {
+	valid := map[string]Selector{
+		"main":            {{tag: "main"}},
+		"DIV.content":     {{tag: "div", classes: []string{"content"}}},
+		"#docs":           {{id: "docs"}},
+		".a.b#c":          {{id: "c", classes: []string{"a", "b"}}},
+		"main, .content":  {{tag: "main"}, {classes: []string{"content"}}},
+		"article#post-1_": {{tag: "article", id: "post-1_"}},
+	}
+	for input, expected := range valid {
+		actual, err := ParseSelector(input)
+		if err != nil {
+			t.Errorf("parse %q: %v", input, err)
+		} else if fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Errorf("expected %q to be parsed as %v, got %v", input, expected, actual)
+		}
+	}
+	for _, input := range []string{"", "main,", "main p", "div > a", "a[href]", "a:hover", ".", "#a#b", "*"} {
+		if _, err := ParseSelector(input); err == nil {
+			t.Errorf("expected error parsing %q", input)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(`<main id="top" class="content wide"></main>`))
+	main := findElements(doc, "main")[0]
+	tests := map[string]bool{
+		"main":              true,
+		".content":          true,
+		".wide.content":     true,
+		"#top":              true,
+		"main.content#top":  true,
+		"div":               false,
+		".narrow":           false,
+		".content.narrow":   false,
+		"#bottom":           false,
+		"div, .wide, #none": true,
+	}
+	for input, expected := range tests {
+		selector, _ := ParseSelector(input)
+		if actual := selector.Matches(main); actual != expected {
+			t.Errorf("expected %q to match %t, got %t", input, expected, actual)
+		}
+	}
+}
+
+func TestCrawlScope(t *testing.T) {
+	var mu sync.Mutex
+	requested := make([]string, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/", "/docs/intro":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<nav><a href="/">home</a><a href="/blog">blog</a></nav>
+				<main><p>See the <a href="/docs/intro">intro</a>.</p>
+				<div class="note"><a href="/docs/faq">FAQ</a></div></main>
+				<aside class="note"><a href="/docs/api">API</a></aside>
+				<footer><a href="/imprint">imprint</a></footer>`)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.Scope, _ = ParseSelector("main, aside.note")
+	reported := make([]string, 0)
+	CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+		reported = append(reported, r.Link.URL.Path)
+	})
+	sort.Strings(reported)
+	expected := []string{"/", "/docs/api", "/docs/faq", "/docs/intro"}
+	if !isEqual(reported, expected) {
+		t.Errorf("expected results for %v, got %v", expected, reported)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(requested)
+	if !isEqual(requested, expected) {
+		t.Errorf("expected requests for %v, got %v", expected, requested)
+	}
+}
@@ -0,0 +1,109 @@
+package checklinks
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sitemapDocument is either a <urlset> listing pages, or a <sitemapindex>
+// listing further sitemaps.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc string `xml:"loc"`
+}
+
+// LinksFromSitemap fetches the sitemap indicated by the given url using the
+// given client and options, and returns a Link for every <loc> entry of its
+// <urlset>. Sitemap index files (<sitemapindex>) are followed recursively. An
+// error is returned if a sitemap cannot be fetched or parsed. Nil options are
+// the DefaultCrawlOptions.
+func LinksFromSitemap(url string, c *http.Client, opts *CrawlOptions) ([]*Link, error) {
+	return LinksFromSitemapContext(context.Background(), url, c, opts)
+}
+
+// LinksFromSitemapContext is like LinksFromSitemap, but stops fetching the
+// sitemaps when the given context is done.
+func LinksFromSitemapContext(ctx context.Context, url string, c *http.Client, opts *CrawlOptions) ([]*Link, error) {
+	return linksFromSitemap(ctx, url, c, optionsOrDefault(opts), make(map[string]struct{}))
+}
+
+func linksFromSitemap(ctx context.Context, address string, c *http.Client, opts *CrawlOptions, seen map[string]struct{}) ([]*Link, error) {
+	if _, ok := seen[address]; ok {
+		return nil, nil
+	}
+	seen[address] = struct{}{}
+	sitemapURL, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("parse sitemap address %s: %v", address, err)
+	}
+	doc, err := fetchSitemap(ctx, address, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]*Link, 0)
+	switch doc.XMLName.Local {
+	case "urlset":
+		for _, entry := range doc.URLs {
+			link, err := NewLink(strings.TrimSpace(entry.Loc), sitemapURL)
+			if err != nil {
+				return nil, fmt.Errorf("sitemap %s: %v", address, err)
+			}
+			links = append(links, link)
+		}
+	case "sitemapindex":
+		for _, entry := range doc.Sitemaps {
+			link, err := NewLink(strings.TrimSpace(entry.Loc), sitemapURL)
+			if err != nil {
+				return nil, fmt.Errorf("sitemap index %s: %v", address, err)
+			}
+			if link.IsInternal() {
+				link.URL = QualifyInternalURL(sitemapURL, link.URL)
+			}
+			childLinks, err := linksFromSitemap(ctx, link.URL.String(), c, opts, seen)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, childLinks...)
+		}
+	default:
+		return nil, fmt.Errorf("sitemap %s: unexpected root element <%s>", address, doc.XMLName.Local)
+	}
+	return links, nil
+}
+
+func fetchSitemap(ctx context.Context, url string, c *http.Client, opts *CrawlOptions) (*sitemapDocument, error) {
+	request, err := newGetRequest(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	response, err := doRequest(c, request)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, &statusError{http.MethodGet, response.StatusCode, url}
+	}
+	decoded, err := decodeBody(response)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	body := newSizeLimitReader(decoded, opts.MaxBodySize)
+	var doc sitemapDocument
+	if err := xml.NewDecoder(body).Decode(&doc); err != nil {
+		if body.exceeded {
+			return nil, fmt.Errorf("sitemap at %s exceeds max. body size of %d bytes", url, opts.MaxBodySize)
+		}
+		return nil, fmt.Errorf("parse sitemap at %s: %v", url, err)
+	}
+	return &doc, nil
+}
@@ -0,0 +1,109 @@
+package checklinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>%s/sitemap-articles.xml</loc></sitemap>
+	<sitemap><loc>/sitemap-about.xml</loc></sitemap>
+</sitemapindex>
+`
+
+const sitemapArticles = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>%s/articles/eat-more-cheese.html</loc></url>
+	<url>
+		<loc>
+			%s/articles/drink-more-milk.html
+		</loc>
+	</url>
+</urlset>
+`
+
+const sitemapAbout = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>%s/about/</loc></url>
+</urlset>
+`
+
+func TestLinksFromSitemap(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, sitemapIndex, srv.URL)
+		case "/sitemap-articles.xml":
+			fmt.Fprintf(w, sitemapArticles, srv.URL, srv.URL)
+		case "/sitemap-about.xml":
+			fmt.Fprintf(w, sitemapAbout, srv.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	links, err := LinksFromSitemap(srv.URL+"/sitemap.xml", srv.Client(), &opts)
+	if err != nil {
+		t.Fatalf("links from sitemap: %v", err)
+	}
+	expected := []string{
+		srv.URL + "/articles/eat-more-cheese.html",
+		srv.URL + "/articles/drink-more-milk.html",
+		srv.URL + "/about/",
+	}
+	actual := make([]string, 0)
+	for _, link := range links {
+		actual = append(actual, link.URL.String())
+	}
+	if !isEqual(actual, expected) {
+		t.Errorf("expected links %v, got %v", expected, actual)
+	}
+}
+
+func TestLinksFromMissingSitemap(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	if _, err := LinksFromSitemap(srv.URL+"/sitemap.xml", srv.Client(), &opts); err == nil {
+		t.Errorf("expected error for missing sitemap, got none")
+	}
+}
+
+func TestLinksFromSitemapCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, sitemapAbout, "http://"+r.Host)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := DefaultCrawlOptions()
+	_, err := LinksFromSitemapContext(ctx, srv.URL+"/sitemap.xml", srv.Client(), &opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestLinksFromSitemapMaxBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, sitemapArticles, "http://"+r.Host, "http://"+r.Host)
+	}))
+	defer srv.Close()
+
+	opts := DefaultCrawlOptions()
+	opts.MaxBodySize = 128
+	_, err := LinksFromSitemap(srv.URL+"/sitemap.xml", srv.Client(), &opts)
+	if err == nil || !strings.Contains(err.Error(), "exceeds max. body size of 128 bytes") {
+		t.Errorf("expected error about the max. body size, got %v", err)
+	}
+}
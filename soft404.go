@@ -0,0 +1,74 @@
+package checklinks
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxSoft404Size is the max. amount of bytes of a response searched for the
+// Soft404Pattern.
+const maxSoft404Size = 1 << 20
+
+// DefaultSoft404Pattern matches common phrases of error pages that are served
+// with the status 200 OK instead of 404 Not Found.
+var DefaultSoft404Pattern = regexp.MustCompile(`(?i)page not found|404 not found|could not be found|` +
+	`page (does not|doesn't) exist|no longer (exists|available)`)
+
+// soft404Error reports a response with the status 200 OK whose content
+// indicates that the requested page doesn't exist.
+type soft404Error struct {
+	url   string
+	match string
+}
+
+func (e *soft404Error) Error() string {
+	return fmt.Sprintf("soft 404: GET 200 OK %s, but content contains %q", e.url, e.match)
+}
+
+// checkSoft404 reads the given successful response to a request for the given
+// URL, and returns a soft404Error if its content matches the Soft404Pattern of
+// the given options. Only textual responses (e.g. HTML) are checked.
+func checkSoft404(response *http.Response, url string, opts *CrawlOptions) error {
+	if opts.Soft404Pattern == nil || response.StatusCode != http.StatusOK || !isTextual(response) {
+		return nil
+	}
+	body, err := decodeBody(response)
+	if err != nil {
+		return fmt.Errorf("fetch %s: %w", url, err)
+	}
+	limit := int64(maxSoft404Size)
+	if opts.MaxBodySize > 0 && opts.MaxBodySize < limit {
+		limit = opts.MaxBodySize
+	}
+	content, err := io.ReadAll(io.LimitReader(body, limit))
+	if err != nil {
+		return fmt.Errorf("read %s: %v", url, err)
+	}
+	return matchSoft404(content, url, opts)
+}
+
+// matchSoft404 returns a soft404Error if the given content of the given URL
+// matches the Soft404Pattern of the given options, and nil otherwise.
+func matchSoft404(content []byte, url string, opts *CrawlOptions) error {
+	if len(content) > maxSoft404Size {
+		content = content[:maxSoft404Size]
+	}
+	if opts.Soft404Pattern == nil {
+		return nil
+	}
+	if match := opts.Soft404Pattern.Find(content); match != nil {
+		return &soft404Error{url, string(match)}
+	}
+	return nil
+}
+
+// isTextual returns true if the given response has a textual content type,
+// e.g. text/html, or none at all, and false otherwise.
+func isTextual(response *http.Response) bool {
+	contentType := strings.ToLower(response.Header.Get("Content-Type"))
+	return contentType == "" || strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "html") || strings.Contains(contentType, "xml")
+}
@@ -0,0 +1,67 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSoft404(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			fmt.Fprint(w, "<h1>Sorry, this page does not exist.</h1>")
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "page not found")
+		default:
+			fmt.Fprint(w, "<h1>Welcome</h1>")
+		}
+	}))
+	defer external.Close()
+	external.URL = strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<a href="/gone">gone</a><a href="/about">about</a>
+				<a href="%[1]s/missing">missing</a><a href="%[1]s/found">found</a>
+				<a href="%[1]s/image.png">image</a>`, external.URL)
+		case "/gone":
+			fmt.Fprint(w, "<title>404 Not Found</title>")
+		default:
+			fmt.Fprint(w, "<h1>About</h1>")
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+
+	for _, pattern := range []*regexp.Regexp{nil, DefaultSoft404Pattern} {
+		opts.Soft404Pattern = pattern
+		var failed []string
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			if r.Status() == StatusFailed {
+				failed = append(failed, r.String())
+				if r.StatusCode != http.StatusOK {
+					t.Errorf("expected soft 404 %s to have status code 200, got %d", r.Link.URL, r.StatusCode)
+				}
+			}
+		})
+		sort.Strings(failed)
+		var expected []string
+		if pattern != nil {
+			expected = []string{
+				fmt.Sprintf(`FAIL "%s/gone": from "%s/" soft 404: GET 200 OK %s/gone, but content contains "404 Not Found"`,
+					srv.URL, srv.URL, srv.URL),
+				fmt.Sprintf(`FAIL "%s/missing": from "%s/" soft 404: GET 200 OK %s/missing, but content contains "page does not exist"`,
+					external.URL, srv.URL, external.URL),
+			}
+		}
+		if !isEqual(failed, expected) {
+			t.Errorf("expected failures %v, got %v", expected, failed)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package checklinks
+
+import "strings"
+
+// srcsetSpace are the characters separating the URLs and descriptors of a
+// srcset attribute.
+const srcsetSpace = " \t\n\r\f"
+
+// ParseSrcset returns the URLs of the image candidates in the given value of
+// a srcset attribute, e.g. "a.jpg 1x, b.jpg 2x" or "a.jpg 480w, b.jpg 800w",
+// following the parsing rules of the HTML standard: the candidates are
+// separated by commas, but URLs may contain commas, too.
+func ParseSrcset(srcset string) []string {
+	urls := make([]string, 0)
+	s := srcset
+	for {
+		s = strings.TrimLeft(s, srcsetSpace+",")
+		if s == "" {
+			return urls
+		}
+		end := strings.IndexAny(s, srcsetSpace)
+		if end < 0 {
+			end = len(s)
+		}
+		u := s[:end]
+		s = s[end:]
+		if strings.HasSuffix(u, ",") {
+			// a candidate without descriptors
+			u = strings.TrimRight(u, ",")
+		} else {
+			// skip the descriptors up to the next comma outside parentheses
+			var depth, i int
+			for ; i < len(s); i++ {
+				if s[i] == '(' {
+					depth++
+				} else if s[i] == ')' && depth > 0 {
+					depth--
+				} else if s[i] == ',' && depth == 0 {
+					break
+				}
+			}
+			s = s[i:]
+		}
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+}
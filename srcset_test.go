@@ -0,0 +1,26 @@
+package checklinks
+
+import "testing"
+
+var parseSrcsetTests = []struct {
+	srcset string
+	urls   []string
+}{
+	{"", []string{}},
+	{"a.jpg", []string{"a.jpg"}},
+	{"a.jpg 1x, b.jpg 2x", []string{"a.jpg", "b.jpg"}},
+	{"small.jpg 480w, medium.jpg 800w, large.jpg 1200w", []string{"small.jpg", "medium.jpg", "large.jpg"}},
+	{"  a.jpg   1.5x ,\n\tb.jpg\t2x  ", []string{"a.jpg", "b.jpg"}},
+	{"a.jpg, b.jpg 2x", []string{"a.jpg", "b.jpg"}},
+	{"/img/w_100,h_100/a.jpg 1x, /img/w_200,h_200/a.jpg 2x", []string{"/img/w_100,h_100/a.jpg", "/img/w_200,h_200/a.jpg"}},
+	{"a.jpg 100w (future, descriptor), b.jpg 200w", []string{"a.jpg", "b.jpg"}},
+	{",, a.jpg 1x,,", []string{"a.jpg"}},
+}
+
+func TestParseSrcset(t *testing.T) {
+	for _, testCase := range parseSrcsetTests {
+		if actual := ParseSrcset(testCase.srcset); !isEqual(actual, testCase.urls) {
+			t.Errorf("expected srcset %q to be parsed as %q, got %q", testCase.srcset, testCase.urls, actual)
+		}
+	}
+}
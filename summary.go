@@ -0,0 +1,141 @@
+package checklinks
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CrawlSummary aggregates the results of a crawl, e.g. to report metrics. It
+// can be serialized as JSON.
+type CrawlSummary struct {
+	// Total is the number of links processed. A link with additional results,
+	// e.g. a warning about a duplicate link besides the result of checking
+	// it, is counted once, but each of its results is counted by status.
+	Total int `json:"total"`
+
+	// Statuses counts the results by their status, e.g. "OK" or "FAIL" (see
+	// Status.String).
+	Statuses map[string]int `json:"statuses"`
+
+	// StatusCodes counts the results by the HTTP status code of the response
+	// to the link's request, or 0 if no response has been received.
+	StatusCodes map[int]int `json:"status_codes"`
+
+	// ErrorKinds counts the failed results by the kind of their error, e.g.
+	// "dns" or "status" (see ErrorKind.String).
+	ErrorKinds map[string]int `json:"error_kinds"`
+
+	// Failed are the URLs of the failed links in the order of their results.
+	Failed []string `json:"failed"`
+
+	// PageLinks are the numbers of links found on the crawled pages by the
+	// pages' URLs (see Result.Links).
+	PageLinks map[string]int `json:"page_links"`
+
+	// Visited are the sorted URLs (without fragment, see CrawlOptions.Visited)
+	// of the links visited by the crawl and not skipped, e.g. to be passed as
+	// the Visited option of another crawl. They are not recorded with the
+	// CompactVisited option.
+	Visited []string `json:"visited,omitempty"`
+
+	// Elapsed is the duration of the crawl (in nanoseconds in JSON).
+	Elapsed time.Duration `json:"elapsed_ns"`
+
+	// OutputErr is the first error writing the results (or the PageStats and
+	// Summary, see CrawlOptions.SummaryOutput), e.g. because the output file
+	// cannot be written, in which case results are missing from the output.
+	// It is nil if everything has been written.
+	OutputErr error `json:"-"`
+}
+
+// newCrawlSummary creates an empty CrawlSummary.
+func newCrawlSummary() *CrawlSummary {
+	return &CrawlSummary{
+		Statuses:    make(map[string]int),
+		StatusCodes: make(map[int]int),
+		ErrorKinds:  make(map[string]int),
+		Failed:      make([]string, 0),
+		PageLinks:   make(map[string]int),
+	}
+}
+
+// add counts the given result.
+func (s *CrawlSummary) add(result *Result) {
+	status := result.Status()
+	if !result.extra {
+		s.Total++
+	}
+	s.Statuses[status.String()]++
+	s.StatusCodes[result.StatusCode]++
+	if status == StatusFailed {
+		s.ErrorKinds[result.Kind().String()]++
+		s.Failed = append(s.Failed, result.Link.URL.String())
+	}
+	if result.Page {
+		s.PageLinks[result.Link.URL.String()] = result.Links
+	}
+}
+
+// outputFailed records the given error writing the output, unless it is nil
+// or another error has been recorded before.
+func (s *CrawlSummary) outputFailed(err error) {
+	if s.OutputErr == nil {
+		s.OutputErr = err
+	}
+}
+
+// Count returns the number of results with the given status.
+func (s *CrawlSummary) Count(status Status) int {
+	return s.Statuses[status.String()]
+}
+
+// PageStat is the number of links found on a crawled page.
+type PageStat struct {
+	URL   string
+	Links int
+}
+
+// PageStats returns the numbers of links found on the crawled pages, sorted by
+// the number of links in descending order, and by the pages' URLs.
+func (s *CrawlSummary) PageStats() []PageStat {
+	stats := make([]PageStat, 0, len(s.PageLinks))
+	for u, links := range s.PageLinks {
+		stats = append(stats, PageStat{URL: u, Links: links})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Links != stats[j].Links {
+			return stats[i].Links > stats[j].Links
+		}
+		return stats[i].URL < stats[j].URL
+	})
+	return stats
+}
+
+// WritePageStats writes the PageStats as a table to the given writer: a line
+// for every page with the number of links, right-aligned, and the page's URL.
+func (s *CrawlSummary) WritePageStats(w io.Writer) error {
+	stats := s.PageStats()
+	if len(stats) == 0 {
+		return nil
+	}
+	width := len(strconv.Itoa(stats[0].Links))
+	for _, stat := range stats {
+		if _, err := fmt.Fprintf(w, "%*d %s\n", width, stat.Links, stat.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns a one-line summary such as "Checked 412 links in 12.5s: 398
+// OK, 6 ignored, 8 failed", as written at the end of a crawl.
+func (s *CrawlSummary) String() string {
+	counts := make(map[Status]int)
+	for _, status := range []Status{StatusOK, StatusIgnored, StatusSkipped, StatusFailed, StatusWarning} {
+		counts[status] = s.Count(status)
+	}
+	return summarize(s.Total, counts, s.Elapsed)
+}
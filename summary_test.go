@@ -0,0 +1,194 @@
+package checklinks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCrawlSummary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a><a href="/gone">gone</a>
+				<a href="/secret">secret</a><a href="mailto:info@example.com">mail</a>`)
+		case "/secret":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case "/gone":
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	opts.Output = io.Discard
+
+	summary := CrawlPageContext(context.Background(), mustParse(srv.URL+"/"), opts)
+	if summary.Total != 6 || summary.Count(StatusOK) != 3 || summary.Count(StatusIgnored) != 1 ||
+		summary.Count(StatusFailed) != 2 {
+		t.Errorf("unexpected counts in summary %+v", summary)
+	}
+	expectedCodes := map[int]int{http.StatusOK: 3, http.StatusNotFound: 1, http.StatusForbidden: 1, 0: 1}
+	if fmt.Sprint(summary.StatusCodes) != fmt.Sprint(expectedCodes) {
+		t.Errorf("expected status codes %v, got %v", expectedCodes, summary.StatusCodes)
+	}
+	if summary.ErrorKinds["status"] != 2 {
+		t.Errorf("expected 2 failures of kind status, got %v", summary.ErrorKinds)
+	}
+	if len(summary.Failed) != 2 {
+		t.Errorf("expected 2 failed URLs, got %v", summary.Failed)
+	}
+	expectedLinks := map[string]int{srv.URL + "/": 5, srv.URL + "/a": 0, srv.URL + "/b": 0}
+	if fmt.Sprint(summary.PageLinks) != fmt.Sprint(expectedLinks) {
+		t.Errorf("expected links per page %v, got %v", expectedLinks, summary.PageLinks)
+	}
+	if summary.Elapsed <= 0 {
+		t.Errorf("expected a positive elapsed time, got %v", summary.Elapsed)
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatalf("marshal summary: %v", err)
+	}
+	var decoded CrawlSummary
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal summary %s: %v", data, err)
+	}
+	if decoded.String() != summary.String() || decoded.StatusCodes[http.StatusNotFound] != 1 {
+		t.Errorf("expected %s to decode to %+v, got %+v", data, summary, decoded)
+	}
+}
+
+func TestPageStats(t *testing.T) {
+	summary := newCrawlSummary()
+	summary.PageLinks = map[string]int{
+		"https://example.com/":        12,
+		"https://example.com/empty":   0,
+		"https://example.com/links":   140,
+		"https://example.com/about":   12,
+		"https://example.com/contact": 3,
+	}
+	var table strings.Builder
+	if err := summary.WritePageStats(&table); err != nil {
+		t.Fatalf("write page stats: %v", err)
+	}
+	expected := `140 https://example.com/links
+ 12 https://example.com/
+ 12 https://example.com/about
+  3 https://example.com/contact
+  0 https://example.com/empty
+`
+	if table.String() != expected {
+		t.Errorf("expected page stats\n%s\ngot\n%s", expected, table.String())
+	}
+}
+
+func TestCrawlPagesContext(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/slow">slow</a>`)
+		default:
+			// The crawl is interrupted while waiting for a slow page.
+			cancel(errors.New("interrupted"))
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+	var output strings.Builder
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	opts.ReportIgnored = true
+	opts.Output = &output
+
+	summary := CrawlPagesContext(ctx, []*url.URL{mustParse(srv.URL + "/")}, opts)
+	if summary.Count(StatusOK) != 1 || summary.Count(StatusSkipped) != 1 {
+		t.Errorf("expected the page to be OK, and the slow link to be skipped, got %v", summary.Statuses)
+	}
+	if !strings.Contains(output.String(), "skipped: interrupted") {
+		t.Errorf("expected the skipped link to be reported with the cause, got %q", output.String())
+	}
+}
+
+// failingWriter fails every write with its error.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestCrawlSummaryOutputErr(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<a href="/gone">gone</a>`)
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	diskFull := errors.New("no space left on device")
+	opts.Output = failingWriter{diskFull}
+
+	summary := CrawlPageContext(context.Background(), mustParse(srv.URL+"/"), opts)
+	if !errors.Is(summary.OutputErr, diskFull) {
+		t.Errorf("expected output error %v, got %v", diskFull, summary.OutputErr)
+	}
+	opts.Output = io.Discard
+	if summary := CrawlPageContext(context.Background(), mustParse(srv.URL+"/"), opts); summary.OutputErr != nil {
+		t.Errorf("expected no output error, got %v", summary.OutputErr)
+	}
+}
+
+func TestCrawlSummaryTotal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/a">a again</a>`)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Summary = false
+	opts.Output = io.Discard
+	opts.ReportDuplicates = true
+
+	summary := CrawlPageContext(context.Background(), mustParse(srv.URL+"/"), opts)
+	if summary.Total != 2 || summary.Count(StatusOK) != 2 || summary.Count(StatusWarning) != 1 {
+		t.Errorf("expected 2 links with 2 OK results and 1 warning, got %+v", summary)
+	}
+	if !strings.HasPrefix(summary.String(), "Checked 2 links in ") {
+		t.Errorf("expected summary of 2 links, got %s", summary)
+	}
+}
+
+func TestSummaryOutput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">a</a>`)
+		}
+	}))
+	defer srv.Close()
+	opts := DefaultCrawlOptions()
+	opts.Output = io.Discard
+	opts.PageStats = true
+	var output strings.Builder
+	opts.SummaryOutput = &output
+
+	summary := CrawlPageContext(context.Background(), mustParse(srv.URL+"/"), opts)
+	expected := fmt.Sprintf("1 %s/\n0 %s/a\n%s\n", srv.URL, srv.URL, summary)
+	if output.String() != expected {
+		t.Errorf("expected summary output\n%s\ngot\n%s", expected, output.String())
+	}
+}
@@ -0,0 +1,109 @@
+package checklinks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Validator checks a link found on a crawled page against a policy, e.g. that
+// it uses https, and returns an error if the link violates it. Validators are
+// called for every link to be checked (see CrawlOptions.Validators), and must
+// not modify the link.
+type Validator func(*Link) error
+
+// Validators are the built-in validators by name, e.g. to be configured by
+// the user.
+var Validators = map[string]Validator{
+	"https":             RequireHTTPS,
+	"no-localhost":      ForbidLocalhost,
+	"trailing-slash":    RequireTrailingSlash,
+	"no-trailing-slash": ForbidTrailingSlash,
+}
+
+// ValidatorNames returns the names of the built-in Validators in alphabetical
+// order.
+func ValidatorNames() []string {
+	names := make([]string, 0, len(Validators))
+	for name := range Validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewValidator returns the built-in validator with the given name, or an error
+// if there is no such validator.
+func NewValidator(name string) (Validator, error) {
+	validator, ok := Validators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown validator %q (use one of %s)", name, strings.Join(ValidatorNames(), ", "))
+	}
+	return validator, nil
+}
+
+var errInsecureLink = errors.New("insecure link: http instead of https")
+
+// RequireHTTPS is a Validator failing the links using http instead of https.
+func RequireHTTPS(l *Link) error {
+	if l.URL.Scheme == "http" {
+		return errInsecureLink
+	}
+	return nil
+}
+
+// ForbidLocalhost is a Validator failing the links to localhost or loopback
+// addresses (e.g. 127.0.0.1) found on pages of other hosts, which are
+// leftovers of development that don't work for anybody else.
+func ForbidLocalhost(l *Link) error {
+	if isLocalhost(l.URL.Hostname()) && !isLocalhost(l.Orig.Hostname()) {
+		return fmt.Errorf("link to local host %s", l.URL.Host)
+	}
+	return nil
+}
+
+// isLocalhost returns true if the given host name is localhost (or one of its
+// subdomains), or a loopback address, and false otherwise.
+func isLocalhost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// RequireTrailingSlash is a Validator warning about the internal links to pages
+// whose paths don't end with a slash (e.g. /about instead of /about/), for
+// sites whose canonical URLs all do, so that the links are not redirected.
+// Links to files (e.g. /about.html or /logo.png) are not affected.
+func RequireTrailingSlash(l *Link) error {
+	if hasTrailingSlashPolicy(l) && !strings.HasSuffix(l.URL.Path, "/") {
+		return fmt.Errorf("%w: trailing slash missing: %s (expected %s/)", errWarning, l.URL.Path, l.URL.Path)
+	}
+	return nil
+}
+
+// ForbidTrailingSlash is a Validator warning about the internal links to pages
+// whose paths end with a slash (e.g. /about/ instead of /about), for sites
+// whose canonical URLs don't, so that the links are not redirected. Links to
+// the root path (/) are not affected.
+func ForbidTrailingSlash(l *Link) error {
+	if hasTrailingSlashPolicy(l) && strings.HasSuffix(l.URL.Path, "/") {
+		return fmt.Errorf("%w: trailing slash: %s (expected %s)", errWarning, l.URL.Path, strings.TrimRight(l.URL.Path, "/"))
+	}
+	return nil
+}
+
+// hasTrailingSlashPolicy returns true if the given link is subject to a
+// trailing slash convention: an internal link to a page other than the root,
+// whose path doesn't end with a file extension, and false otherwise.
+func hasTrailingSlashPolicy(l *Link) bool {
+	if !l.IsInternal() || !l.isNavigational() || strings.Trim(l.URL.Path, "/") == "" {
+		return false
+	}
+	return path.Ext(strings.TrimSuffix(l.URL.Path, "/")) == ""
+}
@@ -0,0 +1,125 @@
+package checklinks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var validatorTests = []struct {
+	validator Validator
+	link      string
+	page      string
+	valid     bool
+}{
+	{RequireHTTPS, "https://example.com/", "https://example.com/", true},
+	{RequireHTTPS, "http://example.com/", "https://example.com/", false},
+	{RequireHTTPS, "file:///docs/index.html", "file:///", true},
+	{ForbidLocalhost, "https://example.com/", "https://example.com/", true},
+	{ForbidLocalhost, "http://localhost:8080/", "https://example.com/", false},
+	{ForbidLocalhost, "http://app.localhost/", "https://example.com/", false},
+	{ForbidLocalhost, "http://127.0.0.2/", "https://example.com/", false},
+	{ForbidLocalhost, "http://[::1]:3000/", "https://example.com/", false},
+	{ForbidLocalhost, "http://localhost.example.com/", "https://example.com/", true},
+	{ForbidLocalhost, "http://localhost:8080/about", "http://localhost:8080/", true},
+	{ForbidLocalhost, "http://127.0.0.1:3000/", "http://localhost:8080/", true},
+	{RequireTrailingSlash, "https://example.com/about/", "https://example.com/", true},
+	{RequireTrailingSlash, "https://example.com/about", "https://example.com/", false},
+	{RequireTrailingSlash, "https://example.com/docs/intro?lang=en#top", "https://example.com/", false},
+	{RequireTrailingSlash, "https://example.com/about.html", "https://example.com/", true},
+	{RequireTrailingSlash, "https://example.com", "https://example.com/", true},
+	{RequireTrailingSlash, "https://other.com/about", "https://example.com/", true},
+	{ForbidTrailingSlash, "https://example.com/about", "https://example.com/", true},
+	{ForbidTrailingSlash, "https://example.com/about/", "https://example.com/", false},
+	{ForbidTrailingSlash, "https://example.com/", "https://example.com/", true},
+	{ForbidTrailingSlash, "https://example.com/v1.2/", "https://example.com/", true},
+	{ForbidTrailingSlash, "https://other.com/about/", "https://example.com/", true},
+}
+
+func TestValidators(t *testing.T) {
+	for _, testCase := range validatorTests {
+		l := &Link{URL: mustParse(testCase.link), Orig: mustParse(testCase.page), Element: "a"}
+		if err := testCase.validator(l); (err == nil) != testCase.valid {
+			t.Errorf("expected %s on %s to be valid: %t, got %v", testCase.link, testCase.page, testCase.valid, err)
+		}
+		if l.IsInternal() && l.URL.Scheme == "https" {
+			// Links to resources (e.g. images) are not subject to the
+			// trailing slash conventions.
+			l.Element = "img"
+			if err := RequireTrailingSlash(l); err != nil {
+				t.Errorf("expected image %s to be valid, got %v", testCase.link, err)
+			}
+			if err := ForbidTrailingSlash(l); err != nil {
+				t.Errorf("expected image %s to be valid, got %v", testCase.link, err)
+			}
+		}
+	}
+}
+
+func TestNewValidator(t *testing.T) {
+	for _, name := range ValidatorNames() {
+		if _, err := NewValidator(name); err != nil {
+			t.Errorf("create validator %s: %v", name, err)
+		}
+	}
+	if _, err := NewValidator("no-typos"); err == nil || !strings.Contains(err.Error(), "https, no-localhost") {
+		t.Errorf("expected error listing the validators, got %v", err)
+	}
+}
+
+func TestCrawlValidators(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/old/page">old</a><a href="/about">about</a>`)
+		case "/about":
+			fmt.Fprint(w, `<a href="/old/page">old</a><a href="/">home</a>`)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer srv.Close()
+	errOldLink := errors.New("link to the old section")
+	noOldLinks := func(l *Link) error {
+		if strings.HasPrefix(l.URL.Path, "/old/") {
+			return errOldLink
+		}
+		return nil
+	}
+
+	for _, allSources := range []bool{false, true} {
+		opts := DefaultCrawlOptions()
+		opts.Validators = []Validator{noOldLinks}
+		opts.AllSources = allSources
+		var failed, checked []string
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			if r.Status() == StatusFailed && errors.Is(r.Err, errOldLink) {
+				for _, source := range r.sources() {
+					failed = append(failed, source.Path+" -> "+r.Link.URL.Path)
+				}
+			} else if r.Status() == StatusOK {
+				checked = append(checked, r.Link.URL.Path)
+			}
+		})
+		sort.Strings(failed)
+		if expected := []string{"/ -> /old/page", "/about -> /old/page"}; !isEqual(failed, expected) {
+			t.Errorf("expected policy violations %v (all sources: %t), got %v", expected, allSources, failed)
+		}
+		sort.Strings(checked)
+		if expected := []string{"/", "/about", "/old/page"}; !isEqual(checked, expected) {
+			t.Errorf("expected links %v to be checked nonetheless (all sources: %t), got %v", expected, allSources, checked)
+		}
+	}
+}
+
+func TestTrailingSlashWarning(t *testing.T) {
+	l := &Link{URL: mustParse("https://example.com/about"), Orig: mustParse("https://example.com/"), Element: "a"}
+	result := &Result{Err: RequireTrailingSlash(l), Link: l}
+	if result.Status() != StatusWarning {
+		t.Errorf("expected a missing trailing slash to be a warning, got %s: %v", result.Status(), result.Err)
+	}
+}
@@ -0,0 +1,88 @@
+package checklinks
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// visitedSet records the visit keys (see visitKey) of the links visited by a
+// crawl.
+type visitedSet interface {
+	// add records the given key, and returns true if it hasn't been recorded
+	// before, or false otherwise.
+	add(key string) bool
+
+	// contains returns true if the given key has been recorded, and false
+	// otherwise.
+	contains(key string) bool
+
+	// keys returns the sorted keys recorded, or nil if they are not kept.
+	keys() []string
+}
+
+// newVisitedSet returns a hashedSet if compact is true, or a stringSet
+// otherwise.
+func newVisitedSet(compact bool) visitedSet {
+	if compact {
+		return make(hashedSet)
+	}
+	return make(stringSet)
+}
+
+// stringSet records the keys as they are.
+type stringSet map[string]struct{}
+
+func (s stringSet) add(key string) bool {
+	if _, ok := s[key]; ok {
+		return false
+	}
+	s[key] = struct{}{}
+	return true
+}
+
+func (s stringSet) contains(key string) bool {
+	_, ok := s[key]
+	return ok
+}
+
+func (s stringSet) keys() []string {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hashedSet records the 64-bit FNV-1a hashes of the keys instead of the keys,
+// which takes a fraction of the memory for long URLs. Two different keys have
+// the same hash with a probability of 2^-64, so that a link is mistaken for
+// another one already visited, and not checked, with a probability of about
+// n^2 / 2^65 among n links, e.g. 3 * 10^-8 for a million links, or 3 * 10^-4
+// for a hundred million links.
+type hashedSet map[uint64]struct{}
+
+// hashKey returns the 64-bit FNV-1a hash of the given key.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func (s hashedSet) add(key string) bool {
+	h := hashKey(key)
+	if _, ok := s[h]; ok {
+		return false
+	}
+	s[h] = struct{}{}
+	return true
+}
+
+func (s hashedSet) contains(key string) bool {
+	_, ok := s[hashKey(key)]
+	return ok
+}
+
+func (s hashedSet) keys() []string {
+	return nil
+}
@@ -0,0 +1,71 @@
+package checklinks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestVisitedSet(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		set := newVisitedSet(compact)
+		keys := []string{"https://example.com/", "https://example.com/a", "https://example.com/a?b=c", ""}
+		for _, key := range keys {
+			if set.contains(key) {
+				t.Errorf("expected %q not to be contained before being added (compact: %t)", key, compact)
+			}
+			if !set.add(key) {
+				t.Errorf("expected %q to be added (compact: %t)", key, compact)
+			}
+		}
+		for _, key := range keys {
+			if !set.contains(key) || set.add(key) {
+				t.Errorf("expected %q to be contained after being added (compact: %t)", key, compact)
+			}
+		}
+		if set.contains("https://example.com/b") {
+			t.Errorf("expected other key not to be contained (compact: %t)", compact)
+		}
+		if actual := set.keys(); compact && actual != nil {
+			t.Errorf("expected no keys of compact set, got %v", actual)
+		} else if expected := []string{"", "https://example.com/", "https://example.com/a", "https://example.com/a?b=c"}; !compact && !isEqual(actual, expected) {
+			t.Errorf("expected keys %v, got %v", expected, actual)
+		}
+	}
+}
+
+func TestCrawlCompactVisited(t *testing.T) {
+	// Every page links to the next ones and back to the first one, so that
+	// the same links are found many times.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/%d", &n)
+		if n > 20 {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<a href="/">home</a><a href="/%d">next</a><a href="/%d#top">after next</a>`, n+1, n+2)
+	}))
+	defer srv.Close()
+
+	results := make([]map[string]Status, 0)
+	for _, compact := range []bool{false, true} {
+		opts := DefaultCrawlOptions()
+		opts.CompactVisited = compact
+		statuses := make(map[string]Status)
+		var total int
+		CrawlPageFunc(mustParse(srv.URL+"/"), opts, func(r *Result) {
+			statuses[r.Link.URL.Path] = r.Status()
+			total++
+		})
+		if total != len(statuses) {
+			t.Errorf("expected every link to be reported once (compact: %t), got %d results for %d links", compact, total, len(statuses))
+		}
+		results = append(results, statuses)
+	}
+	if !reflect.DeepEqual(results[0], results[1]) {
+		t.Errorf("expected the same results with compact visited links, got %v and %v", results[0], results[1])
+	}
+}